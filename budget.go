@@ -0,0 +1,449 @@
+package certcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is reported if an operation would exceed the
+// client's Budget or the account's remaining credit
+var ErrBudgetExceeded = errors.New("certcenter: budget exceeded")
+
+// Budget configures the spending guard of a client (see WithBudget).
+// Zero values don't restrict spending.
+type Budget struct {
+	// MaxOrderPrice refuses single operations quoted above it
+	MaxOrderPrice float64
+	// MaxTotal caps the sum spent through the client
+	MaxTotal float64
+	// MinCredit is the part of the account's credit (Limit - Used)
+	// which is never spent
+	MinCredit float64
+	// LimitTTL is how long a Limit response is relied on (default: 1 minute)
+	LimitTTL time.Duration
+}
+
+// WithBudget guards Order, CreateVoucher, Reissue and RedeemVoucher.
+// Each of them is quoted first and refused with ErrBudgetExceeded if
+// the price exceeds the budget or the account's remaining credit, as
+// reported by Limit. Reissues and redemptions only cost what they add to
+// the order or voucher they refer to, eg. further SubjectAltNames or
+// servers; otherwise they pass unquoted.
+//
+// The quoted price is reserved while the request is in flight, so
+// concurrent goroutines can't overspend together. Batch jobs may
+// reserve their total upfront (see BudgetGuard.Reserve). If the outcome
+// of an operation is unknown (eg. the connection broke), its price stays
+// reserved until it is settled (see BudgetGuard.Resolve).
+func WithBudget(budget Budget) Option {
+	return func(c *Client) {
+		if budget.LimitTTL <= 0 {
+			budget.LimitTTL = time.Minute
+		}
+		c.budget = &BudgetGuard{client: c, budget: budget}
+	}
+}
+
+// BudgetGuard keeps track of the reservations and spending of a client
+type BudgetGuard struct {
+	client *Client
+	budget Budget
+
+	mu        sync.Mutex
+	credit    float64     // Limit - Used
+	fetched   time.Time   // when credit was requested
+	reserved  float64     // held by open reservations
+	spent     float64     // committed in total
+	unbilled  []charge    // committed, but possibly not yet in credit
+	unsettled []unsettled // reserved, outcome unknown
+}
+
+type charge struct {
+	at     time.Time
+	amount float64
+}
+
+// unsettled is a guarded operation whose outcome is unknown
+type unsettled struct {
+	r              *Reservation
+	partnerOrderID string    // empty if the order can't be looked up
+	since          time.Time // when the operation was sent
+}
+
+// BudgetStats is a snapshot of a BudgetGuard
+type BudgetStats struct {
+	Credit    float64 // remaining credit according to the last Limit
+	Reserved  float64 // held by open reservations, including Unsettled
+	Unsettled float64 // held for operations whose outcome is unknown
+	Spent     float64 // committed through the client in total
+	Available float64 // what may still be reserved
+}
+
+// BudgetGuard returns the guard configured by WithBudget, or nil
+func (c *Client) BudgetGuard() *BudgetGuard {
+	return c.budget
+}
+
+// Stats returns a snapshot of the guard's bookkeeping
+func (g *BudgetGuard) Stats() BudgetStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stats := BudgetStats{
+		Credit:    g.credit,
+		Reserved:  g.reserved,
+		Spent:     g.spent,
+		Available: g.available(),
+	}
+	for _, u := range g.unsettled {
+		stats.Unsettled += u.r.amount
+	}
+	return stats
+}
+
+// available returns what may still be reserved. g.mu must be held.
+func (g *BudgetGuard) available() float64 {
+	available := g.credit - g.budget.MinCredit - g.reserved
+	for _, c := range g.unbilled {
+		available -= c.amount
+	}
+	if g.budget.MaxTotal > 0 {
+		if total := g.budget.MaxTotal - g.spent - g.reserved; total < available {
+			available = total
+		}
+	}
+	return available
+}
+
+// refresh fetches the account's credit unless it's recent enough
+func (g *BudgetGuard) refresh(ctx context.Context) error {
+	g.mu.Lock()
+	fresh := !g.fetched.IsZero() && time.Since(g.fetched) < g.budget.LimitTTL
+	g.mu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	started := time.Now()
+	res, err := g.client.Limit(ctx)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if started.Before(g.fetched) {
+		return nil
+	}
+	g.credit = res.LimitInfo.Limit - res.LimitInfo.Used
+	g.fetched = started
+	// charges committed before the request are part of Used now
+	unbilled := g.unbilled[:0]
+	for _, c := range g.unbilled {
+		if !c.at.Before(started) {
+			unbilled = append(unbilled, c)
+		}
+	}
+	g.unbilled = unbilled
+	// and so are operations which can't be looked up, if they have
+	// been charged at all
+	pending := g.unsettled[:0]
+	for _, u := range g.unsettled {
+		if u.partnerOrderID == "" && u.since.Before(started) {
+			u.r.release()
+			continue
+		}
+		pending = append(pending, u)
+	}
+	g.unsettled = pending
+	return nil
+}
+
+// Resolve settles the operations whose outcome is unknown. Orders with
+// a PartnerOrderID are looked up: their price is spent if they have
+// been placed, and released otherwise. Other operations can't be told
+// apart, so their price is held until the next Limit, which includes it
+// if they have been charged; they don't count towards MaxTotal.
+//
+// Resolve is called before each guarded operation, so there is usually
+// no need to call it yourself.
+func (g *BudgetGuard) Resolve(ctx context.Context) error {
+	g.mu.Lock()
+	var lookups []unsettled
+	pending := g.unsettled[:0]
+	for _, u := range g.unsettled {
+		if u.partnerOrderID != "" {
+			lookups = append(lookups, u)
+		} else {
+			pending = append(pending, u)
+		}
+	}
+	g.unsettled = pending
+	g.mu.Unlock()
+
+	var err error
+	for i, u := range lookups {
+		var order *OrderInfo
+		if order, err = g.client.placedOrder(ctx, u.partnerOrderID, u.since); err != nil {
+			g.mu.Lock()
+			g.unsettled = append(g.unsettled, lookups[i:]...)
+			g.mu.Unlock()
+			break
+		}
+		if order != nil {
+			u.r.commit()
+		} else {
+			u.r.Release()
+		}
+	}
+	return err
+}
+
+// Reserve holds amount of the budget, eg. for a batch of orders. Pass
+// the reservation along with ContextWithReservation to make guarded
+// operations draw from it. Release it once the batch is done.
+func (g *BudgetGuard) Reserve(ctx context.Context, amount float64) (*Reservation, error) {
+	if err := g.refresh(ctx); err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if available := g.available(); amount > available {
+		return nil, fmt.Errorf("%w: %.2f requested, %.2f available", ErrBudgetExceeded, amount, available)
+	}
+	g.reserved += amount
+	return &Reservation{guard: g, amount: amount}, nil
+}
+
+// Reservation is a part of the budget held for upcoming operations
+type Reservation struct {
+	guard  *BudgetGuard
+	parent *Reservation
+	amount float64
+	done   bool
+}
+
+// Remaining returns the amount which is still held
+func (r *Reservation) Remaining() float64 {
+	r.guard.mu.Lock()
+	defer r.guard.mu.Unlock()
+	return r.amount
+}
+
+// Release gives the remaining amount back to the budget. Operations
+// drawing from the reservation in the meantime are not affected.
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	g := r.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r.release()
+}
+
+// release is Release with g.mu held
+func (r *Reservation) release() {
+	if r.done {
+		return
+	}
+	if r.parent != nil && !r.parent.done {
+		r.parent.amount += r.amount
+	} else {
+		r.guard.reserved -= r.amount
+	}
+	r.amount, r.done = 0, true
+}
+
+// commit marks the reservation as spent
+func (r *Reservation) commit() {
+	g := r.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r.done {
+		return
+	}
+	g.reserved -= r.amount
+	g.spent += r.amount
+	g.unbilled = append(g.unbilled, charge{at: time.Now(), amount: r.amount})
+	r.amount, r.done = 0, true
+}
+
+// draw holds amount of r for a single operation
+func (r *Reservation) draw(amount float64) (*Reservation, error) {
+	r.guard.mu.Lock()
+	defer r.guard.mu.Unlock()
+	if r.done || amount > r.amount {
+		return nil, fmt.Errorf("%w: %.2f requested, %.2f reserved", ErrBudgetExceeded, amount, r.amount)
+	}
+	r.amount -= amount
+	return &Reservation{guard: r.guard, parent: r, amount: amount}, nil
+}
+
+type reservationKey struct{}
+
+// ContextWithReservation makes guarded operations using ctx draw from
+// r instead of reserving on their own
+func ContextWithReservation(ctx context.Context, r *Reservation) context.Context {
+	return context.WithValue(ctx, reservationKey{}, r)
+}
+
+// hold quotes a guarded operation and reserves its price. It returns
+// nil for other operations.
+func (g *BudgetGuard) hold(ctx context.Context, operation string, request interface{}) (*Reservation, error) {
+	if g == nil {
+		return nil, nil
+	}
+	price, ok, err := g.price(ctx, request)
+	if err != nil || !ok {
+		return nil, err
+	}
+	// a failed lookup leaves the operations reserved, which is safe
+	_ = g.Resolve(ctx)
+	if g.budget.MaxOrderPrice > 0 && price > g.budget.MaxOrderPrice {
+		return nil, fmt.Errorf("%w: %s quoted at %.2f, the maximum is %.2f", ErrBudgetExceeded, operation, price, g.budget.MaxOrderPrice)
+	}
+	if r, ok := ctx.Value(reservationKey{}).(*Reservation); ok && r != nil && r.guard == g {
+		return r.draw(price)
+	}
+	return g.Reserve(ctx, price)
+}
+
+// settle commits or releases the hold of an operation sent at since.
+// Only requests the API has rejected (4xx, or "success": false) are
+// released. If the outcome is unknown (eg. the connection broke, or a
+// 5xx which may have hidden a placed order), the price stays reserved
+// until Resolve finds out.
+func (r *Reservation) settle(ctx context.Context, err error, request interface{}, since time.Time) {
+	if r == nil {
+		return
+	}
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		r.commit()
+		return
+	case errors.As(err, &apiErr) && apiErr.StatusCode < 500:
+		r.Release()
+		return
+	}
+	u := unsettled{r: r, since: since}
+	if order, ok := request.(*OrderRequest); ok && order.OrderParameters != nil {
+		u.partnerOrderID = order.OrderParameters.PartnerOrderID
+	}
+	g := r.guard
+	g.mu.Lock()
+	g.unsettled = append(g.unsettled, u)
+	g.mu.Unlock()
+	if u.partnerOrderID != "" && ctx.Err() == nil {
+		_ = g.Resolve(ctx)
+	}
+}
+
+// price quotes a guarded operation. Reissues and redemptions are
+// quoted at what they add to the order or voucher they refer to (eg.
+// further SubjectAltNames); they aren't guarded if they add nothing.
+func (g *BudgetGuard) price(ctx context.Context, request interface{}) (float64, bool, error) {
+	var params *OrderParameters
+	switch r := request.(type) {
+	case *OrderRequest:
+		params = r.OrderParameters
+	case *CreateVoucherRequest:
+		params = &r.OrderParameters
+	case *RedeemVoucherRequest:
+		return g.redemptionPrice(ctx, r)
+	case *ReissueRequest:
+		return g.reissuePrice(ctx, r)
+	}
+	// requests without parameters are left to the API to reject
+	if params == nil || params.ProductCode == "" {
+		return 0, false, nil
+	}
+	price, err := g.quote(ctx, params.ProductCode, sanCount(params), params.ValidityPeriod, params.ServerCount)
+	if err != nil {
+		return 0, false, err
+	}
+	return price, true, nil
+}
+
+// redemptionPrice quotes the SubjectAltNames and servers a redemption
+// requests beyond those paid for with the voucher
+func (g *BudgetGuard) redemptionPrice(ctx context.Context, r *RedeemVoucherRequest) (float64, bool, error) {
+	if r.OrderParameters == nil || r.VoucherCode == "" {
+		return 0, false, nil
+	}
+	res, err := g.client.GetVoucher(ctx, &GetVoucherRequest{VoucherCode: r.VoucherCode})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res.Vouchers) == 0 {
+		return 0, false, nil
+	}
+	return g.extraPrice(ctx, &res.Vouchers[0].OrderParameters, sanCount(r.OrderParameters), r.OrderParameters.ServerCount)
+}
+
+// reissuePrice quotes the SubjectAltNames a reissue's CSR requests
+// beyond those paid for with the order
+func (g *BudgetGuard) reissuePrice(ctx context.Context, r *ReissueRequest) (float64, bool, error) {
+	csr, err := ParseCSR(r.OrderParameters.CSR)
+	if err != nil {
+		// left to the API to reject
+		return 0, false, nil
+	}
+	_, sans := csr.MismatchedNames(nil)
+	res, err := g.client.GetOrder(ctx, &GetOrderRequest{CertCenterOrderID: r.CertCenterOrderID, IncludeOrderParameters: true})
+	if err != nil {
+		return 0, false, err
+	}
+	paid := &res.OrderInfo.OrderParameters
+	return g.extraPrice(ctx, paid, len(sans), paid.ServerCount)
+}
+
+// extraPrice quotes what raising the SubjectAltNames and servers paid
+// for to sans and servers costs on top
+func (g *BudgetGuard) extraPrice(ctx context.Context, paid *OrderParameters, sans, servers int) (float64, bool, error) {
+	paidSANs := sanCount(paid)
+	if sans <= paidSANs && servers <= paid.ServerCount {
+		return 0, false, nil
+	}
+	if sans < paidSANs {
+		sans = paidSANs
+	}
+	if servers < paid.ServerCount {
+		servers = paid.ServerCount
+	}
+	before, err := g.quote(ctx, paid.ProductCode, paidSANs, paid.ValidityPeriod, paid.ServerCount)
+	if err != nil {
+		return 0, false, err
+	}
+	after, err := g.quote(ctx, paid.ProductCode, sans, paid.ValidityPeriod, servers)
+	if err != nil {
+		return 0, false, err
+	}
+	if after <= before {
+		return 0, false, nil
+	}
+	return after - before, true, nil
+}
+
+func (g *BudgetGuard) quote(ctx context.Context, productCode string, sans, validityPeriod, servers int) (float64, error) {
+	res, err := g.client.Quote(ctx, &QuoteRequest{
+		ProductCode:         productCode,
+		SubjectAltNameCount: sans,
+		ValidityPeriod:      validityPeriod,
+		ServerCount:         servers,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.Price, nil
+}
+
+// sanCount returns the number of SubjectAltNames params are charged for
+func sanCount(params *OrderParameters) int {
+	if n := len(params.SubjectAltNames); n > params.SubjectAltNameCount {
+		return n
+	}
+	return params.SubjectAltNameCount
+}
@@ -0,0 +1,247 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+)
+
+func TestBudgetRefusesOrders(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{MaxOrderPrice: 50, MaxTotal: 30}))
+	ctx := context.Background()
+
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "")); err != nil {
+		t.Fatal(err)
+	}
+	wildcard := newOrder(t, "*.example.com", "")
+	wildcard.OrderParameters.ProductCode = "Comodo.PositiveSSLWildcard"
+	if _, err := c.Order(ctx, wildcard); !errors.Is(err, certcenter.ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded above MaxOrderPrice", err)
+	}
+	if _, err := c.Order(ctx, newOrder(t, "www.example.org", "")); !errors.Is(err, certcenter.ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded above MaxTotal", err)
+	}
+	if n := srv.Calls("Order"); n != 1 {
+		t.Errorf("Order reached the server %d times, want 1", n)
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 19 || stats.Reserved != 0 {
+		t.Errorf("got %+v, want 19 spent and nothing reserved", stats)
+	}
+}
+
+func TestBudgetReservation(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{}))
+	ctx := context.Background()
+
+	reservation, err := c.BudgetGuard().Reserve(ctx, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = certcenter.ContextWithReservation(ctx, reservation)
+	for _, cn := range []string{"a.example.com", "b.example.com"} {
+		if _, err := c.Order(ctx, newOrder(t, cn, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Order(ctx, newOrder(t, "c.example.com", "")); !errors.Is(err, certcenter.ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded once the reservation is used up", err)
+	}
+	if got := reservation.Remaining(); got != 2 {
+		t.Errorf("%.2f remaining, want 2", got)
+	}
+	reservation.Release()
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 38 || stats.Reserved != 0 {
+		t.Errorf("got %+v, want 38 spent and nothing reserved", stats)
+	}
+}
+
+func TestBudgetReissueAndRedeem(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{}))
+	ctx := context.Background()
+
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.ProductCode = "Comodo.PositiveSSLMDC"
+	placed, err := c.Order(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CompleteOrder(placed.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	voucher, err := c.CreateVoucher(ctx, &certcenter.CreateVoucherRequest{
+		OrderParameters: certcenter.OrderParameters{ProductCode: "Comodo.PositiveSSLMDC", ValidityPeriod: 12},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotes := srv.Calls("Quote")
+
+	// a new key for the same names costs nothing
+	_, err = c.Reissue(ctx, &certcenter.ReissueRequest{
+		CertCenterOrderID: placed.CertCenterOrderID,
+		OrderParameters:   certcenter.ReissueOrderParameters{CSR: newCSR(t, "www.example.com")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Quote"); n != quotes {
+		t.Errorf("%d quotes for a reissue without further SANs", n-quotes)
+	}
+	if err := srv.CompleteOrder(placed.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+
+	// further SANs are charged on top of the order and the voucher
+	pemCSR, _, err := csr.Generate(&csr.Request{CommonName: "www.example.com", SubjectAltNames: []string{"example.com"}}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Reissue(ctx, &certcenter.ReissueRequest{
+		CertCenterOrderID: placed.CertCenterOrderID,
+		OrderParameters:   certcenter.ReissueOrderParameters{CSR: pemCSR},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redemption := newOrder(t, "www.example.org", "").OrderParameters
+	redemption.SubjectAltNames = []string{"example.org", "mail.example.org"}
+	_, err = c.RedeemVoucher(ctx, &certcenter.RedeemVoucherRequest{
+		VoucherCode:     voucher.VoucherCode,
+		OrderParameters: redemption,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 39 for the order, 39 for the voucher, 30 per SAN
+	limit, err := c.Limit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 168 || stats.Reserved != 0 || limit.LimitInfo.Used != 168 {
+		t.Errorf("got %+v and %.2f used, want 168 spent", stats, limit.LimitInfo.Used)
+	}
+
+	// and refused if they exceed the budget
+	c = srv.Client(certcenter.WithBudget(certcenter.Budget{MaxOrderPrice: 20}))
+	voucher, err = c.CreateVoucher(ctx, &certcenter.CreateVoucherRequest{
+		OrderParameters: certcenter.OrderParameters{ProductCode: "Comodo.PositiveSSL", ValidityPeriod: 12},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redemption = newOrder(t, "www.example.net", "").OrderParameters
+	redemption.ServerCount = 5 // 4 more than licensed, at 19 each
+	_, err = c.RedeemVoucher(ctx, &certcenter.RedeemVoucherRequest{
+		VoucherCode:     voucher.VoucherCode,
+		OrderParameters: redemption,
+	})
+	if !errors.Is(err, certcenter.ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded", err)
+	}
+	if n := srv.Calls("RedeemVoucher"); n != 1 {
+		t.Errorf("RedeemVoucher reached the server %d times, want 1", n)
+	}
+}
+
+func TestBudgetUnknownOutcomePlaced(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{}))
+
+	// the order is placed, but the response gets lost
+	srv.FailNext("Order", certcentertest.Failure{Drop: true, Processed: true})
+	if _, err := c.Order(context.Background(), newOrder(t, "www.example.com", "batch-1")); err == nil {
+		t.Fatal("expected the dropped connection to fail the order")
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 19 || stats.Reserved != 0 || stats.Unsettled != 0 {
+		t.Errorf("got %+v, want the order looked up and 19 spent", stats)
+	}
+}
+
+func TestBudgetUnknownOutcomeNotPlaced(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{}))
+	ctx := context.Background()
+
+	// the order never arrives, and it can't be looked up right away
+	srv.FailNext("Order", certcentertest.Failure{Drop: true})
+	srv.FailNext("GetModifiedOrders", certcentertest.Failure{StatusCode: 503})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "batch-1")); err == nil {
+		t.Fatal("expected the dropped connection to fail the order")
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 0 || stats.Reserved != 19 || stats.Unsettled != 19 {
+		t.Fatalf("got %+v, want 19 reserved until the outcome is known", stats)
+	}
+
+	// the next order finds out that the first one doesn't exist
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "batch-2")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 19 || stats.Reserved != 0 || stats.Unsettled != 0 {
+		t.Errorf("got %+v, want only the second order spent", stats)
+	}
+}
+
+func TestBudgetUnknownOutcomeServerError(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{}))
+	ctx := context.Background()
+
+	// a rejected order is released
+	invalid := newOrder(t, "www.example.com", "batch-1")
+	invalid.OrderParameters.ValidityPeriod = 36
+	if _, err := c.Order(ctx, invalid); !errors.Is(err, certcenter.ErrInvalidRequest) {
+		t.Fatalf("got %v, want ErrInvalidRequest", err)
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Reserved != 0 || stats.Unsettled != 0 {
+		t.Fatalf("got %+v, want nothing reserved", stats)
+	}
+
+	// a 5xx may hide a placed order, which is looked up
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: 502, Processed: true})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "batch-2")); err == nil {
+		t.Fatal("expected the 502 to fail the order")
+	}
+	if stats := c.BudgetGuard().Stats(); stats.Spent != 19 || stats.Reserved != 0 || stats.Unsettled != 0 {
+		t.Errorf("got %+v, want the order looked up and 19 spent", stats)
+	}
+}
+
+func TestBudgetUnknownOutcomeWithoutPartnerOrderID(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithBudget(certcenter.Budget{LimitTTL: time.Nanosecond}))
+	ctx := context.Background()
+
+	srv.FailNext("Order", certcentertest.Failure{Drop: true, Processed: true})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "")); err == nil {
+		t.Fatal("expected the dropped connection to fail the order")
+	}
+	guard := c.BudgetGuard()
+	if stats := guard.Stats(); stats.Unsettled != 19 || stats.Spent != 0 {
+		t.Fatalf("got %+v, want 19 unsettled", stats)
+	}
+
+	// the next Limit includes the charge, so the hold is released
+	if _, err := guard.Reserve(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	stats := guard.Stats()
+	if stats.Unsettled != 0 || stats.Reserved != 0 || stats.Credit != srv.Limit-19 {
+		t.Errorf("got %+v, want nothing reserved and a credit of %.2f", stats, srv.Limit-19)
+	}
+}
@@ -0,0 +1,309 @@
+// Package bundle assembles the certificates of a fulfilled order into
+// a correctly ordered chain and exports it in the formats servers and
+// appliances expect.
+//
+//	res, err := client.GetOrder(ctx, &certcenter.GetOrderRequest{
+//		CertCenterOrderID: id,
+//		IncludeFulfillment: true,
+//	})
+//	..
+//	b, err := bundle.FromFulfillment(&res.OrderInfo.Fulfillment)
+//	..
+//	if err := b.SetKey(key); err != nil {
+//		..
+//	}
+//	err = b.WriteFile("/etc/ssl/example.pem", bundle.FullChainPEM, "")
+//	err = b.WriteFile("/etc/ssl/example.pfx", bundle.PKCS12, password)
+package bundle
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	certcenter "certcenter.com/go"
+)
+
+// Bundle is a leaf certificate with its chain and, optionally, its key
+type Bundle struct {
+	Leaf *x509.Certificate
+	// Chain holds the intermediates, starting with the leaf's issuer
+	Chain []*x509.Certificate
+	// Root is set if the input contained the self-signed root
+	Root *x509.Certificate
+	// Key is the leaf's private key (see SetKey)
+	Key crypto.Signer
+}
+
+// Parse builds a bundle from any mix of PEM-encoded certificates and
+// PKCS#7 structures (PEM or base64), eg. the Certificate, Intermediate
+// and PKCS7 fields of a fulfillment. Certificates which are not part
+// of the leaf's chain are dropped.
+func Parse(inputs ...string) (*Bundle, error) {
+	var certs []*x509.Certificate
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		parsed, err := parseInput(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range parsed {
+			if !seen[string(cert.Raw)] {
+				seen[string(cert.Raw)] = true
+				certs = append(certs, cert)
+			}
+		}
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("bundle: no certificates found")
+	}
+
+	leaf, err := findLeaf(certs)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Leaf: leaf}
+	for current := leaf; ; {
+		issuer := findIssuer(current, certs)
+		if issuer == nil {
+			break
+		}
+		if isSelfSigned(issuer) {
+			b.Root = issuer
+			break
+		}
+		b.Chain = append(b.Chain, issuer)
+		current = issuer
+	}
+	return b, nil
+}
+
+// FromFulfillment builds a bundle from the Certificate and Intermediate
+// of an order's fulfillment
+func FromFulfillment(f *certcenter.Fulfillment) (*Bundle, error) {
+	return Parse(f.Certificate, f.Intermediate)
+}
+
+// FromOrderResult builds a bundle from the fulfillment of an AlwaysOnSSL
+// order
+func FromOrderResult(res *certcenter.OrderResult) (*Bundle, error) {
+	return Parse(res.Fulfillment.Certificate, res.Fulfillment.Intermediate, res.Fulfillment.PKCS7)
+}
+
+// parseInput returns all certificates found in a single input
+func parseInput(input string) ([]*x509.Certificate, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(input, "-----BEGIN") {
+		// bare base64, as PKCS#7 is sometimes passed around
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(input), ""))
+		if err != nil {
+			return nil, errors.New("bundle: neither PEM nor base64")
+		}
+		return parseDER(der)
+	}
+
+	var certs []*x509.Certificate
+	rest := []byte(input)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, cert)
+		case "PKCS7", "CMS":
+			parsed, err := parsePKCS7(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, parsed...)
+		}
+	}
+	return certs, nil
+}
+
+// parseDER accepts a PKCS#7 structure or a single certificate
+func parseDER(der []byte) ([]*x509.Certificate, error) {
+	if certs, err := parsePKCS7(der); err == nil {
+		return certs, nil
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.New("bundle: neither PKCS#7 nor a certificate")
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func issued(issuer, cert *x509.Certificate) bool {
+	return cert != issuer && bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.CheckSignatureFrom(issuer) == nil
+}
+
+func findIssuer(cert *x509.Certificate, certs []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range certs {
+		if issued(candidate, cert) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// findLeaf returns the certificate which didn't issue any other one,
+// preferring end-entity certificates over CAs
+func findLeaf(certs []*x509.Certificate) (*x509.Certificate, error) {
+	var leaves, cas []*x509.Certificate
+	for _, cert := range certs {
+		issuer := false
+		for _, other := range certs {
+			if issued(cert, other) {
+				issuer = true
+				break
+			}
+		}
+		switch {
+		case issuer:
+		case cert.IsCA:
+			cas = append(cas, cert)
+		default:
+			leaves = append(leaves, cert)
+		}
+	}
+	if len(leaves) == 0 {
+		leaves = cas
+	}
+	switch len(leaves) {
+	case 0:
+		return nil, errors.New("bundle: no leaf certificate found")
+	case 1:
+		return leaves[0], nil
+	}
+	return nil, fmt.Errorf("bundle: %d unrelated leaf certificates found", len(leaves))
+}
+
+// SetKey sets the leaf's private key after checking that it matches
+func (b *Bundle) SetKey(key crypto.Signer) error {
+	if !MatchesKey(b.Leaf, key) {
+		return errors.New("bundle: private key does not match the certificate")
+	}
+	b.Key = key
+	return nil
+}
+
+// MatchesKey reports whether key belongs to cert
+func MatchesKey(cert *x509.Certificate, key crypto.Signer) bool {
+	pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key != nil && pub.Equal(key.Public())
+}
+
+// Verify checks the chain against roots (nil means the system roots)
+func (b *Bundle) Verify(roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range b.Chain {
+		intermediates.AddCert(cert)
+	}
+	_, err := b.Leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// Format selects an export format (see Encode)
+type Format int
+
+const (
+	// FullChainPEM is the leaf followed by the intermediates
+	FullChainPEM Format = iota
+	// LeafPEM is the leaf only
+	LeafPEM
+	// ChainPEM holds the intermediates only
+	ChainPEM
+	// DER is the binary leaf certificate
+	DER
+	// PKCS7 is a certs-only PKCS#7 (.p7b) with leaf and intermediates,
+	// DER-encoded
+	PKCS7
+	// PKCS7PEM is PKCS7 in PEM
+	PKCS7PEM
+	// PKCS12 is a password-protected PKCS#12 (.pfx/.p12) with the key,
+	// the leaf and the intermediates. It requires SetKey.
+	PKCS12
+	// KeyPEM is the private key as PKCS#8. It requires SetKey.
+	KeyPEM
+)
+
+// Encode exports the bundle. password is used by PKCS12 only.
+func (b *Bundle) Encode(format Format, password string) ([]byte, error) {
+	switch format {
+	case FullChainPEM:
+		return encodePEM(append([]*x509.Certificate{b.Leaf}, b.Chain...)), nil
+	case LeafPEM:
+		return encodePEM([]*x509.Certificate{b.Leaf}), nil
+	case ChainPEM:
+		return encodePEM(b.Chain), nil
+	case DER:
+		return b.Leaf.Raw, nil
+	case PKCS7, PKCS7PEM:
+		der, err := encodePKCS7(append([]*x509.Certificate{b.Leaf}, b.Chain...))
+		if err != nil || format == PKCS7 {
+			return der, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: der}), nil
+	case PKCS12:
+		if b.Key == nil {
+			return nil, errors.New("bundle: PKCS#12 requires the private key")
+		}
+		return encodePKCS12(b.Key, b.Leaf, b.Chain, password)
+	case KeyPEM:
+		if b.Key == nil {
+			return nil, errors.New("bundle: no private key set")
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(b.Key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, fmt.Errorf("bundle: unknown format %d", format)
+}
+
+// WriteFile encodes the bundle and writes it to name. Files holding
+// the private key are only readable by the owner.
+func (b *Bundle) WriteFile(name string, format Format, password string) error {
+	data, err := b.Encode(format, password)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if format == PKCS12 || format == KeyPEM {
+		perm = 0600
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+func encodePEM(certs []*x509.Certificate) []byte {
+	var b bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.Bytes()
+}
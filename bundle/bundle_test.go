@@ -0,0 +1,345 @@
+package bundle_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/bundle"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+)
+
+// issue orders a certificate for www.example.com with a key of type
+// keyType and returns the order's fulfillment
+func issue(t *testing.T, srv *certcentertest.Server, keyType csr.KeyType) (*certcenter.Fulfillment, crypto.Signer) {
+	t.Helper()
+	pemCSR, key, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, keyType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := srv.Client().Order(context.Background(), &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 12,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := srv.Order(res.CertCenterOrderID)
+	return &order.Fulfillment, key
+}
+
+// pemCertificate returns raw as PEM
+func pemCertificate(raw []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}))
+}
+
+func TestFromFulfillment(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	f, _ := issue(t, srv, csr.ECDSAP256)
+
+	b, err := bundle.FromFulfillment(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Leaf.Subject.CommonName != "www.example.com" || len(b.Chain) != 1 ||
+		!b.Chain[0].Equal(srv.CA.Intermediate) || b.Root != nil || b.Key != nil {
+		t.Fatalf("got leaf %s, %d intermediates, root %v", b.Leaf.Subject, len(b.Chain), b.Root)
+	}
+	if err := b.Verify(srv.CA.Roots()); err != nil {
+		t.Error(err)
+	}
+	if err := b.Verify(nil); err == nil {
+		t.Error("expected the fake CA not to be trusted by the system")
+	}
+
+	// the order of the input doesn't matter, the root is kept apart
+	b, err = bundle.Parse(pemCertificate(srv.CA.Root.Raw), f.Intermediate, f.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Leaf.Subject.CommonName != "www.example.com" || len(b.Chain) != 1 || b.Root == nil || !b.Root.Equal(srv.CA.Root) {
+		t.Fatalf("got leaf %s, %d intermediates, root %v", b.Leaf.Subject, len(b.Chain), b.Root)
+	}
+}
+
+func TestFromOrderResult(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	pemCSR, key, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// AlwaysOnSSL certificates come with the order result
+	res, err := srv.Client().Order(context.Background(), &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "AlwaysOnSSL.AlwaysOnSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 180,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bundle.FromOrderResult(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Chain) != 1 || !bundle.MatchesKey(b.Leaf, key) {
+		t.Errorf("got leaf %s, %d intermediates", b.Leaf.Subject, len(b.Chain))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	first, _ := issue(t, srv, csr.ECDSAP256)
+	second, _ := issue(t, srv, csr.ECDSAP256)
+
+	for _, inputs := range [][]string{
+		{},
+		{"", " "},
+		{"not base64!"},
+		{base64.StdEncoding.EncodeToString([]byte("neither PKCS#7 nor a certificate"))},
+		{first.Certificate, second.Certificate, first.Intermediate},
+	} {
+		if _, err := bundle.Parse(inputs...); err == nil {
+			t.Errorf("%d inputs: expected an error", len(inputs))
+		}
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	f, key := issue(t, srv, csr.ECDSAP256)
+	b, err := bundle.FromFulfillment(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := csr.GenerateKey(csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetKey(other); err == nil || b.Key != nil {
+		t.Error("expected a mismatched key to be refused")
+	}
+	if err := b.SetKey(nil); err == nil {
+		t.Error("expected a nil key to be refused")
+	}
+	if err := b.SetKey(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	f, key := issue(t, srv, csr.ECDSAP256)
+	b, err := bundle.FromFulfillment(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []bundle.Format{bundle.PKCS12, bundle.KeyPEM, bundle.Format(99)} {
+		if _, err := b.Encode(format, "secret"); err == nil {
+			t.Errorf("format %d: expected an error", format)
+		}
+	}
+	if err := b.SetKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, intermediate := b.Leaf.Raw, srv.CA.Intermediate.Raw
+	for _, tc := range []struct {
+		format bundle.Format
+		want   [][]byte // certificates, in order
+	}{
+		{bundle.FullChainPEM, [][]byte{leaf, intermediate}},
+		{bundle.LeafPEM, [][]byte{leaf}},
+		{bundle.ChainPEM, [][]byte{intermediate}},
+	} {
+		data, err := b.Encode(tc.format, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]byte
+		for rest := data; ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			got = append(got, block.Bytes)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("format %d: got %d certificates, want %d", tc.format, len(got), len(tc.want))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], tc.want[i]) {
+				t.Errorf("format %d: certificate %d differs", tc.format, i)
+			}
+		}
+	}
+
+	if der, _ := b.Encode(bundle.DER, ""); !bytes.Equal(der, leaf) {
+		t.Error("DER isn't the leaf certificate")
+	}
+	keyPEM, err := b.Encode(bundle.KeyPEM, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, err := csr.ParseKey(string(keyPEM)); err != nil || !bundle.MatchesKey(b.Leaf, parsed) {
+		t.Errorf("KeyPEM doesn't hold the key: %v", err)
+	}
+
+	// PKCS#7 can be read back, DER as well as PEM
+	for _, format := range []bundle.Format{bundle.PKCS7, bundle.PKCS7PEM} {
+		data, err := b.Encode(format, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := string(data)
+		if format == bundle.PKCS7 {
+			input = base64.StdEncoding.EncodeToString(data)
+		}
+		parsed, err := bundle.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !parsed.Leaf.Equal(b.Leaf) || len(parsed.Chain) != 1 || !parsed.Chain[0].Equal(b.Chain[0]) {
+			t.Errorf("format %d: got leaf %s, %d intermediates", format, parsed.Leaf.Subject, len(parsed.Chain))
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	f, key := issue(t, srv, csr.ECDSAP256)
+	b, err := bundle.FromFulfillment(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetKey(key); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	for _, tc := range []struct {
+		name   string
+		format bundle.Format
+		perm   os.FileMode
+	}{
+		{"fullchain.pem", bundle.FullChainPEM, 0644},
+		{"key.pem", bundle.KeyPEM, 0600},
+		{"bundle.pfx", bundle.PKCS12, 0600},
+	} {
+		name := filepath.Join(dir, tc.name)
+		if err := b.WriteFile(name, tc.format, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the umask may take away more
+		if perm := info.Mode().Perm(); perm&^tc.perm != 0 {
+			t.Errorf("%s: got %v, want at most %v", tc.name, perm, tc.perm)
+		}
+	}
+}
+
+// openssl runs the openssl command line tool, skipping the test if it
+// isn't installed
+func openssl(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	path, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not found")
+	}
+	out, err := exec.Command(path, args...).CombinedOutput()
+	return string(out), err
+}
+
+func TestOpenSSL(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	for _, keyType := range []csr.KeyType{csr.RSA2048, csr.ECDSAP256, csr.ECDSAP384} {
+		f, key := issue(t, srv, keyType)
+		b, err := bundle.FromFulfillment(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetKey(key); err != nil {
+			t.Fatal(err)
+		}
+
+		p7b := filepath.Join(dir, string(keyType)+".p7b")
+		if err := b.WriteFile(p7b, bundle.PKCS7, ""); err != nil {
+			t.Fatal(err)
+		}
+		out, err := openssl(t, "pkcs7", "-inform", "DER", "-in", p7b, "-print_certs")
+		if err != nil {
+			t.Fatalf("%s: openssl pkcs7: %v\n%s", keyType, err, out)
+		}
+		if !strings.Contains(out, "www.example.com") || !strings.Contains(out, "certcentertest Intermediate CA") {
+			t.Errorf("%s: openssl pkcs7 printed\n%s", keyType, out)
+		}
+
+		// a password which isn't plain ASCII
+		const password = "s3cret-Pässword"
+		pfx := filepath.Join(dir, string(keyType)+".pfx")
+		if err := b.WriteFile(pfx, bundle.PKCS12, password); err != nil {
+			t.Fatal(err)
+		}
+		out, err = openssl(t, "pkcs12", "-info", "-in", pfx, "-passin", "pass:"+password, "-nodes")
+		if err != nil {
+			t.Fatalf("%s: openssl pkcs12: %v\n%s", keyType, err, out)
+		}
+		if !strings.Contains(out, "MAC: sha256") || !strings.Contains(out, "PBES2, PBKDF2, AES-256-CBC") {
+			t.Errorf("%s: unexpected algorithms\n%s", keyType, out)
+		}
+		var certs int
+		var parsed crypto.Signer
+		for rest := []byte(out); ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			switch block.Type {
+			case "CERTIFICATE":
+				certs++
+			case "PRIVATE KEY":
+				if parsed, err = csr.ParseKey(string(pem.EncodeToMemory(block))); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if certs != 2 || !bundle.MatchesKey(b.Leaf, parsed) {
+			t.Errorf("%s: openssl found %d certificates and key %T\n%s", keyType, certs, parsed, out)
+		}
+
+		if out, err := openssl(t, "pkcs12", "-info", "-in", pfx, "-passin", "pass:wrong", "-nodes"); err == nil {
+			t.Errorf("%s: openssl accepted the wrong password\n%s", keyType, out)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11
+	for _, tc := range []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+			"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+			"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	} {
+		got := hex.EncodeToString(pbkdf2([]byte(tc.password), []byte(tc.salt), tc.iterations, 64))
+		if got != tc.want {
+			t.Errorf("%s/%s: got %s", tc.password, tc.salt, got)
+		}
+	}
+}
+
+func TestPKCS12KDF(t *testing.T) {
+	// computed with openssl kdf -kdfopt digest:SHA256 .. PKCS12KDF,
+	// which takes the password as is
+	for _, tc := range []struct {
+		password, salt string
+		id             byte
+		iterations     int
+		want           string
+	}{
+		{"secret", "0102030405060708", 3, 2048, "b049161455a1d99f26fd9d5afba6094baf9efc31c69ae4e8c862ab928c6ba75b"},
+		{"smeg", "0a58cf64530d823f", 1, 1, "c6c3e6456ae5b69c048dd8900b8e98a874f1a163244c69ba4eb94a4a3eeae97a8b192353205afe1c"},
+	} {
+		salt, _ := hex.DecodeString(tc.salt)
+		want, _ := hex.DecodeString(tc.want)
+		if got := pkcs12KDF([]byte(tc.password), salt, tc.id, tc.iterations, len(want)); !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x", tc.password, got)
+		}
+	}
+}
+
+func TestBMPString(t *testing.T) {
+	if got := bmpString("Ä€"); !bytes.Equal(got, []byte{0x00, 0xc4, 0x20, 0xac, 0, 0}) {
+		t.Errorf("got %x", got)
+	}
+	if got := bmpString(""); !bytes.Equal(got, []byte{0, 0}) {
+		t.Errorf("got %x", got)
+	}
+}
+
+func TestEncryptKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := encryptKey(key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decrypt it following the parameters
+	var info encryptedPrivateKeyInfo
+	var params pbes2Params
+	var kdf pbkdf2Params
+	var iv []byte
+	if _, err := asn1.Unmarshal(der, &info); err != nil || !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		t.Fatalf("not PBES2: %v", err)
+	}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		t.Fatal(err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) || !kdf.PRF.Algorithm.Equal(oidHMACWithSHA256) ||
+		!params.EncryptionScheme.Algorithm.Equal(oidAES256CBC) || kdf.Iterations != pkcs12Iterations {
+		t.Fatalf("unexpected parameters %+v, %+v", params, kdf)
+	}
+	block, err := aes.NewCipher(pbkdf2([]byte("secret"), kdf.Salt, kdf.Iterations, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, len(info.Data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.Data)
+	plain = plain[:len(plain)-int(plain[len(plain)-1])]
+	decrypted, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(decrypted) {
+		t.Error("decrypted a different key")
+	}
+}
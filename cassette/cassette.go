@@ -0,0 +1,371 @@
+// Package cassette records CertCenter API exchanges to a file and replays
+// them later, so code built on certcenter.go can be regression-tested
+// offline and deterministically.
+//
+//	rec, err := cassette.New("testdata/order.json", cassette.Replay)
+//	if err != nil {
+//		..
+//	}
+//	defer rec.Close()
+//
+//	client := certcenter.NewClient(rec.Option(), certcenter.WithBearer(token))
+//
+// Record against the real API once (cassette.Record), commit the cassette
+// file and switch to cassette.Replay. Bearer tokens, kv-storage keys,
+// passwords and private keys are never written to the cassette, neither
+// in headers nor in JSON bodies (eg. the OAuth2_Token of a Profile
+// response or the PrivateKey generated by ValidateName).
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	certcenter "certcenter.com/go"
+)
+
+// Mode selects whether a Recorder records or replays
+type Mode int
+
+const (
+	// Replay serves responses from the cassette and never touches the network
+	Replay Mode = iota
+	// Record sends requests to the API and writes the exchanges to the cassette
+	Record
+)
+
+// ErrNoInteraction is returned (wrapped) in Replay mode for requests
+// which have no unused counterpart in the cassette
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// scrubbedHeaders are the credentials which never end up in a cassette
+var scrubbedHeaders = []string{"Authorization", "X-Api-Key"}
+
+// scrubbedFields are JSON keys (at any depth) holding credentials or
+// key material. Their values are replaced by Redacted.
+var scrubbedFields = []string{
+	"OAuth2_Token", "Password",
+	"PrivateKey", "Passphrase", "KeyPassword", "PKCS12Password",
+}
+
+// Redacted replaces scrubbed credentials in recorded bodies
+const Redacted = "REDACTED"
+
+// Request is the recorded part of an http.Request
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header `json:",omitempty"`
+	Body   Body        `json:",omitempty"`
+}
+
+// Response is the recorded part of an http.Response
+type Response struct {
+	StatusCode int
+	Header     http.Header `json:",omitempty"`
+	Body       Body        `json:",omitempty"`
+}
+
+// Interaction is a single request/response pair
+type Interaction struct {
+	Request  Request
+	Response Response
+}
+
+// Body is a request or response body. It is stored as text where
+// possible and base64-encoded otherwise.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(struct{ Base64 string }{base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+	var encoded struct{ Base64 string }
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = raw
+	return err
+}
+
+// Recorder is an http.RoundTripper which records or replays
+// interactions. Create it with New and Close it when finished.
+type Recorder struct {
+	// Transport sends requests in Record mode (default: http.DefaultTransport)
+	Transport http.RoundTripper
+	// IgnoreParams lists query parameters and top-level JSON body keys
+	// which are not considered when matching requests (eg. "FromDate")
+	IgnoreParams []string
+	// Filter is called on each interaction before it is stored, eg. to
+	// scrub further data. Credentials have already been removed.
+	Filter func(*Interaction)
+
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a Recorder for the cassette file at path. In Replay mode
+// the file has to exist; in Record mode it is (over)written by Close.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == Record {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c struct{ Interactions []Interaction }
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	r.interactions = c.Interactions
+	r.used = make([]bool, len(c.Interactions))
+	return r, nil
+}
+
+// Mode returns the recorder's mode
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Option returns a client option which sends all requests through r.
+// Requests without a matching interaction fail like network errors and
+// are therefore subject to the client's RetryPolicy.
+func (r *Recorder) Option() certcenter.Option {
+	return certcenter.WithRoundTripper(r)
+}
+
+// Interactions returns a copy of the recorded or loaded interactions
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Close writes the cassette file in Record mode
+func (r *Recorder) Close() error {
+	if r.mode != Record {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(struct{ Interactions []Interaction }{r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.mode == Record {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	i := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   scrubBody(body),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       scrubBody(data),
+		},
+	}
+	for _, name := range scrubbedHeaders {
+		i.Request.Header.Del(name)
+	}
+	if r.Filter != nil {
+		r.Filter(&i)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+	return res, nil
+}
+
+// replay answers req with the first unused matching interaction
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := r.key(req.Method, req.URL, body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n, i := range r.interactions {
+		if r.used[n] {
+			continue
+		}
+		u, err := url.Parse(i.Request.URL)
+		if err != nil || r.key(i.Request.Method, u, i.Request.Body) != key {
+			continue
+		}
+		r.used[n] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, req.Method, req.URL.Path)
+}
+
+// key identifies a request by method, path and normalized query and body
+func (r *Recorder) key(method string, u *url.URL, body []byte) string {
+	query := u.Query()
+	for _, name := range r.IgnoreParams {
+		query.Del(name)
+	}
+	return method + " " + u.Path + "?" + query.Encode() + "\n" + string(r.normalize(body))
+}
+
+// normalize re-encodes JSON bodies with sorted keys and scrubbed
+// credentials, leaving out IgnoreParams. Other bodies are compared
+// verbatim.
+func (r *Recorder) normalize(body []byte) []byte {
+	v, ok := decodeJSON(body)
+	if !ok {
+		return body
+	}
+	scrub(v)
+	if m, ok := v.(map[string]interface{}); ok {
+		for _, name := range r.IgnoreParams {
+			delete(m, name)
+		}
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// decodeJSON decodes body, keeping numbers as they are
+func decodeJSON(body []byte) (interface{}, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if len(body) == 0 || dec.Decode(&v) != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// scrubBody replaces credentials in a JSON body. Other bodies, and
+// bodies without credentials, are returned as they are.
+func scrubBody(body []byte) []byte {
+	v, ok := decodeJSON(body)
+	if !ok || !scrub(v) {
+		return body
+	}
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return scrubbed
+}
+
+// scrub replaces the values of scrubbedFields and PEM-encoded private
+// keys in v by Redacted. It reports whether anything has been replaced.
+func scrub(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && s != Redacted && (scrubbedField(key) || privateKey(s)) {
+				v[key] = Redacted
+				changed = true
+			} else if !scrubbedField(key) {
+				changed = scrub(value) || changed
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if s, ok := value.(string); ok && privateKey(s) {
+				v[i] = Redacted
+				changed = true
+			} else {
+				changed = scrub(value) || changed
+			}
+		}
+	}
+	return changed
+}
+
+// privateKey reports whether s contains a PEM-encoded private key,
+// whatever field it has been returned in
+func privateKey(s string) bool {
+	return strings.Contains(s, "PRIVATE KEY-----")
+}
+
+func scrubbedField(key string) bool {
+	for _, name := range scrubbedFields {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package cassette_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/cassette"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+	user := &certcenter.CreateUserRequest{UserData: certcenter.UserData{
+		FullName: "Jane Doe",
+		Email:    "jane@example.com",
+		Username: "jane",
+		Password: "s3cr3t-passw0rd",
+		Roles:    []string{"PROCUREMENT"},
+	}}
+
+	rec, err := cassette.New(path, cassette.Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := srv.Client(rec.Option())
+	profile, err := c.Profile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.OAuth2Token != srv.Token {
+		t.Fatalf("got token %q while recording, want %q", profile.OAuth2Token, srv.Token)
+	}
+	if _, err := c.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{srv.Token, user.Password} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	// replay without the fake server
+	srv.Close()
+	rec, err = cassette.New(path, cassette.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = certcenter.NewClient(rec.Option(), certcenter.WithBaseURL(srv.URL+certcentertest.APIPath))
+	profile, err = c.Profile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.OAuth2Token != cassette.Redacted || profile.CustomerID != 100000 {
+		t.Errorf("got %+v, want the recorded profile with a redacted token", profile)
+	}
+	// requests match although their credentials have been scrubbed
+	if _, err := c.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Profile(ctx); !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("got %v, want ErrNoInteraction", err)
+	}
+}
+
+func TestIgnoreParams(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	rec, _ := cassette.New(path, cassette.Record)
+	c := srv.Client(rec.Option())
+	if _, err := c.Quote(ctx, &certcenter.QuoteRequest{ProductCode: "Comodo.PositiveSSL", ValidityPeriod: 12}); err != nil {
+		t.Fatal(err)
+	}
+	rec.Close()
+
+	rec, err := cassette.New(path, cassette.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = srv.Client(rec.Option())
+	if _, err := c.Quote(ctx, &certcenter.QuoteRequest{ProductCode: "Comodo.PositiveSSL", ValidityPeriod: 24}); !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Fatalf("got %v, want ErrNoInteraction", err)
+	}
+	rec.IgnoreParams = []string{"ValidityPeriod"}
+	if _, err := c.Quote(ctx, &certcenter.QuoteRequest{ProductCode: "Comodo.PositiveSSL", ValidityPeriod: 24}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrivateKeysAreScrubbed(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	rec, _ := cassette.New(path, cassette.Record)
+	c := srv.Client(rec.Option())
+	res, err := c.ValidateName(ctx, &certcenter.ValidateNameRequest{CommonName: "www.example.com", GeneratePrivateKey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("got no private key while recording: %+v", res)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "PRIVATE KEY") {
+		t.Error("cassette contains the private key")
+	}
+	if !strings.Contains(string(data), "CERTIFICATE REQUEST") {
+		t.Error("cassette lacks the CSR")
+	}
+}
@@ -0,0 +1,325 @@
+package certcenter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProductFlags interprets the Features and SANFeatures of a product
+type ProductFlags struct {
+	DV, OV, EV  bool // validation level
+	Wildcard    bool // wildcard common names
+	SAN         bool // SubjectAltNames (see SANMaxHosts)
+	HostOnlySAN bool
+	WildcardSAN bool
+	IDN         bool // internationalized domain names
+	SMIME       bool
+	CodeSign    bool
+	AlwaysOnSSL bool
+}
+
+// Flags returns the typed features of p
+func (p *ProductInfo) Flags() ProductFlags {
+	return ProductFlags{
+		DV:          hasFeature(p.Features, "DV"),
+		OV:          hasFeature(p.Features, "OV"),
+		EV:          hasFeature(p.Features, "EV"),
+		Wildcard:    hasFeature(p.Features, "WILDCARD"),
+		SAN:         hasFeature(p.Features, "SAN") || p.SANMaxHosts > 0,
+		HostOnlySAN: hasFeature(p.SANFeatures, "HostOnlySAN"),
+		WildcardSAN: hasFeature(p.SANFeatures, "WildcardSAN"),
+		IDN:         hasFeature(p.Features, "IDN"),
+		SMIME:       hasFeature(p.Features, "SMIME"),
+		CodeSign:    hasFeature(p.Features, "CODESIGN"),
+		AlwaysOnSSL: hasFeature(p.Features, "AlwaysOnSSL"),
+	}
+}
+
+// ValidationLevel returns "EV", "OV" or "DV", or "" if the product
+// does not state a validation level
+func (p *ProductInfo) ValidationLevel() string {
+	flags := p.Flags()
+	switch {
+	case flags.EV:
+		return "EV"
+	case flags.OV:
+		return "OV"
+	case flags.DV:
+		return "DV"
+	}
+	return ""
+}
+
+// Type returns the ProductType used to filter orders of this product
+func (p *ProductInfo) Type() ProductType {
+	flags := p.Flags()
+	switch {
+	case flags.SMIME:
+		return ProductTypeSMIME
+	case flags.CodeSign:
+		return ProductTypeCodeSign
+	}
+	return ProductTypeSSL
+}
+
+// DefaultCatalogTTL is how long a Catalog keeps product details
+// unless configured otherwise
+const DefaultCatalogTTL = 24 * time.Hour
+
+// Catalog caches the details of all products, so lookups don't need
+// a round trip each. Create it with Client.NewCatalog.
+type Catalog struct {
+	client *Client
+
+	// TTL is how long loaded product details are used
+	TTL time.Duration
+	// CachePath optionally names a file the catalog is kept in, so
+	// it survives restarts
+	CachePath string
+
+	mu         sync.Mutex
+	products   map[string]ProductInfo
+	loaded     time.Time
+	refreshing *catalogRefresh
+}
+
+// catalogRefresh is a refresh in progress, which concurrent lookups of
+// stale products wait for instead of starting their own
+type catalogRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// catalogFile is the format of Catalog.CachePath
+type catalogFile struct {
+	Loaded   time.Time
+	Products []ProductInfo
+}
+
+// NewCatalog returns an empty Catalog which loads the products on
+// first use
+func (c *Client) NewCatalog() *Catalog {
+	return &Catalog{client: c, TTL: DefaultCatalogTTL}
+}
+
+// Refresh loads the details of all products from the API, regardless
+// of the TTL
+func (c *Catalog) Refresh(ctx context.Context) error {
+	res, err := c.client.Products(ctx)
+	if err != nil {
+		return err
+	}
+
+	// fetch the details with a few requests in flight
+	products := make([]ProductInfo, len(res.Products))
+	errs := make([]error, len(res.Products))
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, code := range res.Products {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, code string) {
+			defer func() { <-sem; wg.Done() }()
+			details, err := c.client.ProductDetails(ctx, code)
+			if err == nil {
+				products[i] = details.ProductDetails
+			}
+			errs[i] = err
+		}(i, code)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(products, time.Now())
+	if c.CachePath != "" {
+		return c.save()
+	}
+	return nil
+}
+
+// set replaces the cached products. c.mu must be held.
+func (c *Catalog) set(products []ProductInfo, loaded time.Time) {
+	c.products = make(map[string]ProductInfo, len(products))
+	for _, p := range products {
+		c.products[p.ProductCode] = p
+	}
+	c.loaded = loaded
+}
+
+func (c *Catalog) fresh() bool {
+	return c.products != nil && time.Since(c.loaded) < c.TTL
+}
+
+// load fills the cache from CachePath or from the API, if necessary.
+// Only one refresh runs at a time, the other callers wait for it.
+func (c *Catalog) load(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if c.fresh() {
+			c.mu.Unlock()
+			return nil
+		}
+		if c.CachePath != "" && c.products == nil {
+			c.restore()
+			if c.fresh() {
+				c.mu.Unlock()
+				return nil
+			}
+		}
+		r := c.refreshing
+		if r == nil {
+			r = &catalogRefresh{done: make(chan struct{})}
+			c.refreshing = r
+			c.mu.Unlock()
+			r.err = c.Refresh(ctx)
+			c.mu.Lock()
+			c.refreshing = nil
+			c.mu.Unlock()
+			close(r.done)
+			return r.err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// the refresh failed for a reason of its caller, so try again
+		// with our own context
+		if r.err == nil || !errors.Is(r.err, context.Canceled) && !errors.Is(r.err, context.DeadlineExceeded) {
+			return r.err
+		}
+	}
+}
+
+// restore reads CachePath. c.mu must be held. A missing or broken
+// cache file is ignored, the products are loaded from the API then.
+func (c *Catalog) restore() {
+	data, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return
+	}
+	var file catalogFile
+	if json.Unmarshal(data, &file) == nil {
+		c.set(file.Products, file.Loaded)
+	}
+}
+
+// save writes CachePath atomically. c.mu must be held.
+func (c *Catalog) save() error {
+	data, err := json.Marshal(catalogFile{Loaded: c.loaded, Products: c.sorted(nil)})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.CachePath), filepath.Base(c.CachePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.CachePath)
+}
+
+// sorted returns the products matching filter (all if nil) ordered by
+// ProductCode. c.mu must be held.
+func (c *Catalog) sorted(filter func(*ProductInfo) bool) []ProductInfo {
+	var products []ProductInfo
+	for _, p := range c.products {
+		if filter == nil || filter(&p) {
+			products = append(products, p)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ProductCode < products[j].ProductCode
+	})
+	return products
+}
+
+// Product returns the details of a single product. Unknown product
+// codes are reported as ErrNotFound.
+func (c *Catalog) Product(ctx context.Context, productCode string) (ProductInfo, error) {
+	if err := c.load(ctx); err != nil {
+		return ProductInfo{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.products[productCode]
+	if !ok {
+		return p, fmt.Errorf("%w: product %s", ErrNotFound, productCode)
+	}
+	return p, nil
+}
+
+// Products returns all products matching filter (all if nil), ordered
+// by ProductCode
+func (c *Catalog) Products(ctx context.Context, filter func(*ProductInfo) bool) ([]ProductInfo, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sorted(filter), nil
+}
+
+// WildcardSANProducts returns the products supporting wildcard SubjectAltNames
+func (c *Catalog) WildcardSANProducts(ctx context.Context) ([]ProductInfo, error) {
+	return c.Products(ctx, func(p *ProductInfo) bool {
+		return p.Flags().WildcardSAN
+	})
+}
+
+// DVProducts returns the products which only require domain validation
+func (c *Catalog) DVProducts(ctx context.Context) ([]ProductInfo, error) {
+	return c.Products(ctx, func(p *ProductInfo) bool {
+		return p.ValidationLevel() == "DV"
+	})
+}
+
+// ProductsByCA returns the products issued by ca (eg. "DigiCert")
+func (c *Catalog) ProductsByCA(ctx context.Context, ca string) ([]ProductInfo, error) {
+	return c.Products(ctx, func(p *ProductInfo) bool {
+		return strings.EqualFold(p.CA, ca)
+	})
+}
+
+// ValidateOrder validates request locally against the details of
+// the ordered product (see OrderRequest.ValidateFor)
+func (c *Catalog) ValidateOrder(ctx context.Context, request *OrderRequest) error {
+	return c.validate(ctx, request)
+}
+
+// validate checks request against the details of the product it names
+func (c *Catalog) validate(ctx context.Context, request productValidator) error {
+	if request.productCode() == "" {
+		return request.Validate()
+	}
+	product, err := c.Product(ctx, request.productCode())
+	if errors.Is(err, ErrNotFound) {
+		return ValidationErrors{{Key: "OrderParameters.ProductCode", Msg: "unknown product"}}
+	}
+	if err != nil {
+		return err
+	}
+	return request.ValidateFor(&product)
+}
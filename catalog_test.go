@@ -0,0 +1,240 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+// productCodes returns the codes of products
+func productCodes(products []certcenter.ProductInfo) []string {
+	var codes []string
+	for _, p := range products {
+		codes = append(codes, p.ProductCode)
+	}
+	return codes
+}
+
+func equalCodes(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCatalogLoadsOnce(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		p, err := catalog.Product(ctx, "DigiCert.SecureSiteEV")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ValidationLevel() != "EV" || p.Type() != certcenter.ProductTypeSSL || !p.Flags().SAN {
+			t.Errorf("got %+v", p.Flags())
+		}
+	}
+	if _, err := catalog.Product(ctx, "Example.Unknown"); !errors.Is(err, certcenter.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if n := srv.Calls("Products"); n != 1 {
+		t.Errorf("products listed %d times, want 1", n)
+	}
+	if n, want := srv.Calls("ProductDetails"), len(certcentertest.DefaultProducts); n != want {
+		t.Errorf("product details fetched %d times, want %d", n, want)
+	}
+
+	// expired details are loaded again
+	catalog.TTL = time.Nanosecond
+	if _, err := catalog.Product(ctx, "DigiCert.SecureSiteEV"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Products"); n != 2 {
+		t.Errorf("products listed %d times, want 2", n)
+	}
+}
+
+func TestCatalogRefreshesOnce(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+
+	// concurrent lookups of missing products share a single refresh
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := catalog.Product(context.Background(), "Comodo.PositiveSSL"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if n := srv.Calls("Products"); n != 1 {
+		t.Errorf("products listed %d times, want 1", n)
+	}
+
+	// a refresh given up by its caller is taken over by the others
+	catalog.TTL = time.Nanosecond
+	srv.FailNext("Products", certcentertest.Failure{StatusCode: 503, Delay: 50 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := catalog.Products(ctx, nil)
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if _, err := catalog.Products(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCatalogRefreshError(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+
+	srv.FailNext("ProductDetails", certcentertest.Failure{StatusCode: 503})
+	if _, err := catalog.Products(context.Background(), nil); err == nil {
+		t.Fatal("expected the failed ProductDetails to be reported")
+	}
+	// nothing half-loaded is kept
+	products, err := catalog.Products(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != len(certcentertest.DefaultProducts) {
+		t.Errorf("got %d products, want %d", len(products), len(certcentertest.DefaultProducts))
+	}
+}
+
+func TestCatalogCachePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "products.json")
+	ctx := context.Background()
+
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	first := srv.Client().NewCatalog()
+	first.CachePath = path
+	if _, err := first.Products(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "products.json" {
+		t.Fatalf("got %d files in the cache directory, want only products.json", len(entries))
+	}
+
+	// a restarted process reads the cache instead of the API
+	second := srv.Client().NewCatalog()
+	second.CachePath = path
+	p, err := second.Product(ctx, "Comodo.PositiveSSL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxValidityPeriod != 24 {
+		t.Errorf("got %+v from the cache", p)
+	}
+	if n := srv.Calls("Products"); n != 1 {
+		t.Errorf("products listed %d times, want 1", n)
+	}
+
+	// a broken cache is replaced
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	third := srv.Client().NewCatalog()
+	third.CachePath = path
+	if _, err := third.Product(ctx, "Comodo.PositiveSSL"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Products"); n != 2 {
+		t.Errorf("products listed %d times, want 2", n)
+	}
+}
+
+func TestCatalogFilters(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		list func(context.Context) ([]certcenter.ProductInfo, error)
+		want []string
+	}{
+		{"WildcardSANProducts", catalog.WildcardSANProducts, []string{"Comodo.PositiveSSLMDC"}},
+		{"DVProducts", catalog.DVProducts, []string{
+			"AlwaysOnSSL.AlwaysOnSSL",
+			"Comodo.PositiveSSL",
+			"Comodo.PositiveSSLMDC",
+			"Comodo.PositiveSSLWildcard",
+			"GeoTrust.QuickSSLPremium",
+		}},
+		{"ProductsByCA", func(ctx context.Context) ([]certcenter.ProductInfo, error) {
+			return catalog.ProductsByCA(ctx, "digicert")
+		}, []string{"AlwaysOnSSL.AlwaysOnSSL", "DigiCert.SecureSite", "DigiCert.SecureSiteEV"}},
+	} {
+		products, err := tc.list(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := productCodes(products); !equalCodes(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	smime, err := catalog.Product(ctx, "Comodo.PersonalAuthentication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smime.Type() != certcenter.ProductTypeSMIME || smime.ValidationLevel() != "" {
+		t.Errorf("got type %q, level %q", smime.Type(), smime.ValidationLevel())
+	}
+}
+
+func TestCatalogValidateOrder(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+	ctx := context.Background()
+
+	order := newOrder(t, "www.example.com", "")
+	if err := catalog.ValidateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	order.OrderParameters.ProductCode = "Example.Unknown"
+	err := catalog.ValidateOrder(ctx, order)
+	var invalid certcenter.ValidationErrors
+	if !errors.As(err, &invalid) || !errors.Is(err, certcenter.ErrInvalidRequest) ||
+		len(invalid) != 1 || invalid[0].Key != "OrderParameters.ProductCode" {
+		t.Errorf("got %v, want the unknown product reported", err)
+	}
+}
@@ -0,0 +1,50 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestContextDeadline(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	srv.FailNext("Profile", certcentertest.Failure{Delay: 500 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := srv.Client().Profile(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Profile returned after %v, want it to give up at the deadline", elapsed)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+
+	// cancelled while the order is in flight
+	srv.FailNext("Order", certcentertest.Failure{Delay: 500 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// the kv-storage isn't requested at all once ctx is done
+	_, err := c.KvStore(ctx, &certcenter.KeyValueStoreRequest{Key: "www.example.com", Value: "hash"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := srv.Calls("KvStore"); n != 0 {
+		t.Errorf("kv-storage requested %d times, want 0", n)
+	}
+}
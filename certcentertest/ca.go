@@ -0,0 +1,284 @@
+package certcentertest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CA is a throwaway two-tier certificate authority. The fake server
+// uses it to issue genuine certificates for fulfilled orders.
+type CA struct {
+	Root         *x509.Certificate
+	Intermediate *x509.Certificate
+	// CRLURL is embedded as CRL distribution point in issued certificates
+	CRLURL string
+
+	rootKey         crypto.Signer
+	intermediateKey crypto.Signer
+
+	mu        sync.Mutex
+	serial    int64
+	crlNumber int64
+	revoked   []x509.RevocationListEntry
+}
+
+// IssueRequest describes a certificate to be issued by a CA
+type IssueRequest struct {
+	CSR                    *x509.CertificateRequest
+	SubjectAltNames        []string
+	NotBefore, NotAfter    time.Time
+	SignatureHashAlgorithm string
+	Organization           string
+	// SMIME certificates carry email addresses and EmailProtection
+	SMIME bool
+}
+
+// NewCA creates a root and an intermediate certificate
+func NewCA() (*CA, error) {
+	ca := &CA{serial: time.Now().Unix()}
+	now := time.Now().Add(-time.Hour)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	root := &x509.Certificate{
+		SerialNumber:          ca.nextSerial(),
+		Subject:               pkix.Name{CommonName: "certcentertest Root CA", Organization: []string{"certcentertest"}},
+		NotBefore:             now,
+		NotAfter:              now.AddDate(20, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if ca.Root, err = createCertificate(root, root, rootKey.Public(), rootKey); err != nil {
+		return nil, err
+	}
+
+	intermediateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	intermediate := &x509.Certificate{
+		SerialNumber:          ca.nextSerial(),
+		Subject:               pkix.Name{CommonName: "certcentertest Intermediate CA", Organization: []string{"certcentertest"}},
+		NotBefore:             now,
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	if ca.Intermediate, err = createCertificate(intermediate, ca.Root, intermediateKey.Public(), rootKey); err != nil {
+		return nil, err
+	}
+
+	ca.rootKey = rootKey
+	ca.intermediateKey = intermediateKey
+	return ca, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, pub interface{}, key crypto.Signer) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func (ca *CA) nextSerial() *big.Int {
+	ca.serial++
+	return big.NewInt(ca.serial)
+}
+
+// Roots returns a pool containing the root certificate
+func (ca *CA) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Root)
+	return pool
+}
+
+// signatureAlgorithm maps a SignatureHashAlgorithm order parameter
+// onto the algorithm the intermediate signs with
+func signatureAlgorithm(hash string) x509.SignatureAlgorithm {
+	switch {
+	case strings.Contains(hash, "384"):
+		return x509.ECDSAWithSHA384
+	case strings.Contains(hash, "512"):
+		return x509.ECDSAWithSHA512
+	}
+	return x509.ECDSAWithSHA256
+}
+
+// Issue signs a certificate for the CSR of req
+func (ca *CA) Issue(req IssueRequest) (*x509.Certificate, error) {
+	if req.CSR == nil {
+		return nil, errors.New("certcentertest: no CSR")
+	}
+	if err := req.CSR.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		Subject:            pkix.Name{CommonName: req.CSR.Subject.CommonName},
+		NotBefore:          req.NotBefore,
+		NotAfter:           req.NotAfter,
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		SignatureAlgorithm: signatureAlgorithm(req.SignatureHashAlgorithm),
+	}
+	if req.Organization != "" {
+		template.Subject.Organization = []string{req.Organization}
+		template.Subject.Country = req.CSR.Subject.Country
+		template.Subject.Locality = req.CSR.Subject.Locality
+	}
+	if ca.CRLURL != "" {
+		template.CRLDistributionPoints = []string{ca.CRLURL}
+	}
+	if _, ok := req.CSR.PublicKey.(*rsa.PublicKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	if req.SMIME {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection, x509.ExtKeyUsageClientAuth}
+		template.EmailAddresses = req.CSR.EmailAddresses
+		if len(template.EmailAddresses) == 0 && strings.Contains(req.CSR.Subject.CommonName, "@") {
+			template.EmailAddresses = []string{req.CSR.Subject.CommonName}
+		}
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		names := req.SubjectAltNames
+		if len(names) == 0 {
+			names = req.CSR.DNSNames
+		}
+		template.DNSNames = dedupe(append([]string{req.CSR.Subject.CommonName}, names...))
+	}
+
+	ca.mu.Lock()
+	template.SerialNumber = ca.nextSerial()
+	ca.mu.Unlock()
+
+	return createCertificate(template, ca.Intermediate, req.CSR.PublicKey, ca.intermediateKey)
+}
+
+func dedupe(names []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// revocationReasons maps RevokeReason values onto CRL reason codes
+var revocationReasons = map[string]int{
+	"unspecified":          0,
+	"keyCompromise":        1,
+	"cACompromise":         2,
+	"affiliationChanged":   3,
+	"superseded":           4,
+	"cessationOfOperation": 5,
+	"privilegeWithdrawn":   9,
+}
+
+// Revoke adds cert to the CRL
+func (ca *CA) Revoke(cert *x509.Certificate, reason string, at time.Time) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	ca.revoked = append(ca.revoked, x509.RevocationListEntry{
+		SerialNumber:   cert.SerialNumber,
+		RevocationTime: at,
+		ReasonCode:     revocationReasons[reason],
+	})
+}
+
+// IsRevoked reports whether cert has been revoked
+func (ca *CA) IsRevoked(cert *x509.Certificate) bool {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	for _, entry := range ca.revoked {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CRL returns a freshly signed, DER-encoded certificate revocation list
+func (ca *CA) CRL() ([]byte, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	ca.crlNumber++
+	now := time.Now()
+	return x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(ca.crlNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(24 * time.Hour),
+		RevokedCertificateEntries: ca.revoked,
+	}, ca.Intermediate, ca.intermediateKey)
+}
+
+func encodeCertificates(certs ...*x509.Certificate) string {
+	var b strings.Builder
+	for _, cert := range certs {
+		pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.String()
+}
+
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// encodePKCS7 returns a PEM-encoded, certs-only PKCS#7 SignedData structure
+func encodePKCS7(certs ...*x509.Certificate) (string, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      struct{ ContentType asn1.ObjectIdentifier }{oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		return "", err
+	}
+	der, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: der})), nil
+}
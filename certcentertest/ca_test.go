@@ -0,0 +1,114 @@
+package certcentertest_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func parseCertificate(t *testing.T, data string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		t.Fatal("no PEM block found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestIssuedCertificates(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	request := newOrder(t, "www.example.com")
+	request.OrderParameters.SubjectAltNames = []string{"example.com"}
+	res, err := c.Order(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := srv.Order(res.CertCenterOrderID)
+	f := order.Fulfillment
+	leaf := parseCertificate(t, f.Certificate)
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(f.Intermediate))
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       "example.com",
+		Roots:         srv.CA.Roots(),
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !leaf.NotAfter.Equal(f.EndDate) || !leaf.NotAfter.Equal(order.OrderStatus.EndDate) {
+		t.Errorf("EndDate %s (status %s) doesn't match NotAfter %s", f.EndDate, order.OrderStatus.EndDate, leaf.NotAfter)
+	}
+
+	response, err := http.Get(f.DownloadLinks.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(body) != f.Certificate {
+		t.Error("download link doesn't serve the certificate")
+	}
+
+	if srv.CA.IsRevoked(leaf) {
+		t.Fatal("certificate revoked before Revoke")
+	}
+	if _, err := c.Revoke(ctx, &certcenter.RevokeRequest{CertCenterOrderID: res.CertCenterOrderID, RevokeReason: certcenter.RevokeKeyCompromise}); err != nil {
+		t.Fatal(err)
+	}
+	response, err = http.Get(srv.URL + certcentertest.CRLPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(srv.CA.Intermediate); err != nil {
+		t.Fatal(err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 || crl.RevokedCertificateEntries[0].SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Errorf("CRL doesn't list the revoked certificate")
+	}
+}
+
+func TestAlwaysOnSSL(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+
+	// AlwaysOnSSL orders are issued at once, ValidityPeriod is in days
+	request := newOrder(t, "www.example.com")
+	request.OrderParameters.ProductCode = "AlwaysOnSSL.AlwaysOnSSL"
+	request.OrderParameters.ValidityPeriod = 180
+	res, err := srv.Client().Order(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, _ := srv.Order(res.CertCenterOrderID)
+	if order.OrderStatus.MajorStatus != certcenter.StatusComplete {
+		t.Fatalf("got %s, want %s", order.OrderStatus.MajorStatus, certcenter.StatusComplete)
+	}
+	leaf := parseCertificate(t, order.Fulfillment.Certificate)
+	if days := leaf.NotAfter.Sub(leaf.NotBefore).Hours() / 24; days != 180 {
+		t.Errorf("got %v days, want 180", days)
+	}
+}
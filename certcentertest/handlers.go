@@ -0,0 +1,1118 @@
+package certcentertest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	certcenter "certcenter.com/go"
+)
+
+type handlerFunc func(http.ResponseWriter, *request)
+
+func (s *Server) registerHandlers() {
+	s.handlers = map[string]handlerFunc{
+		"Profile":                       s.profile,
+		"Limit":                         s.limit,
+		"Products":                      s.productList,
+		"ProductDetails":                s.productDetails,
+		"Quote":                         s.quote,
+		"ValidateCSR":                   s.validateCSR,
+		"ApproverList":                  s.approverList,
+		"Order":                         s.order,
+		"GetOrders":                     s.getOrders,
+		"GetModifiedOrders":             s.getModifiedOrders,
+		"GetOrder":                      s.getOrder,
+		"DeleteOrder":                   s.deleteOrder,
+		"Reissue":                       s.reissue,
+		"Revoke":                        s.revoke,
+		"ValidateName":                  s.validateName,
+		"DNSData":                       s.dnsData,
+		"FileData":                      s.fileData,
+		"CreateUser":                    s.createUser,
+		"UpdateUser":                    s.updateUser,
+		"GetUser":                       s.getUser,
+		"DeleteUser":                    s.deleteUser,
+		"CreateVoucher":                 s.createVoucher,
+		"RedeemVoucher":                 s.redeemVoucher,
+		"GetVouchers":                   s.getVouchers,
+		"GetVoucher":                    s.getVouchers,
+		"GetVoucherAnonymously":         s.getVouchers,
+		"GetVoucherOrderAnonymously":    s.getVoucherOrder,
+		"DeleteVoucher":                 s.deleteVoucher,
+		"ResendApproverEmail":           s.resendApproverEmail,
+		"PutApproverEmail":              s.putApproverEmail,
+		"VulnerabilityAssessmentRescan": s.vulnerabilityAssessmentRescan,
+	}
+}
+
+// order is an order kept by the fake server
+type order struct {
+	info  certcenter.OrderInfo
+	price float64
+	// issued certificate and its PEM-encoded PKCS#7 (leaf and intermediate)
+	cert  *x509.Certificate
+	pkcs7 string
+}
+
+// voucher is a voucher kept by the fake server
+type voucher struct {
+	RedeemInfo struct {
+		RedeemDate        time.Time
+		CertCenterOrderID int64
+	}
+	CreationDate    time.Time
+	OrderParameters certcenter.OrderParameters
+	VoucherCode     string
+	Redeemed        bool
+}
+
+func (s *Server) now() time.Time {
+	return s.Now().UTC()
+}
+
+func success() certcenter.BasicResultInfo {
+	return certcenter.BasicResultInfo{Success: true}
+}
+
+func decode(w http.ResponseWriter, r *request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		invalid(w, fieldError{Key: "", Msg: "Invalid JSON: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func flag(q url.Values, name string) bool {
+	v := q.Get(name)
+	return v == "true" || v == "1"
+}
+
+func (s *Server) profile(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, certcenter.ProfileResult{
+		AuthType:        "OAUTH2",
+		AuthorizationID: 1,
+		Country:         "DE",
+		Currency:        "EUR",
+		CustomerID:      100000,
+		Locale:          "en_US",
+		OAuth2Token:     s.Token,
+		Scope:           "UI",
+		Timezone:        "Europe/Berlin",
+	})
+}
+
+func (s *Server) limit(w http.ResponseWriter, r *request) {
+	res := certcenter.LimitResult{BasicResultInfo: success()}
+	res.LimitInfo.Limit = s.Limit
+	res.LimitInfo.Used = s.used
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) productList(w http.ResponseWriter, r *request) {
+	res := certcenter.ProductsResult{BasicResultInfo: success()}
+	for code := range s.products {
+		res.Products = append(res.Products, code)
+	}
+	sort.Strings(res.Products)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) productDetails(w http.ResponseWriter, r *request) {
+	p, ok := s.products[r.URL.Query().Get("ProductCode")]
+	if !ok {
+		invalid(w, fieldError{Key: "ProductCode", Msg: "Unknown ProductCode"})
+		return
+	}
+	writeJSON(w, http.StatusOK, certcenter.ProductDetailsResult{
+		BasicResultInfo: success(),
+		ProductDetails:  p,
+	})
+}
+
+// price looks up what the server charges for p in its price list.
+// Products without one are charged their Price, whatever the options.
+// s.mu must be held.
+func (s *Server) price(p certcenter.ProductInfo, sans, validityPeriod, serverCount int) (float64, bool) {
+	list, ok := s.prices[p.ProductCode]
+	if !ok {
+		return p.Price, true
+	}
+	price, ok := list[validityPeriod]
+	if !ok {
+		if price, ok = list[0]; !ok {
+			return 0, false
+		}
+	}
+	total := price.Base + float64(sans)*price.SAN
+	if p.Licenses > 0 && serverCount > p.Licenses {
+		total += float64(serverCount-p.Licenses) * price.Server
+	}
+	return total, true
+}
+
+// sanCount returns the number of SANs to charge for, which is the
+// larger of SubjectAltNameCount and the SubjectAltNames listed
+func sanCount(params *certcenter.OrderParameters) int {
+	if n := len(params.SubjectAltNames); n > params.SubjectAltNameCount {
+		return n
+	}
+	return params.SubjectAltNameCount
+}
+
+func (s *Server) quote(w http.ResponseWriter, r *request) {
+	q := r.URL.Query()
+	p, ok := s.products[q.Get("ProductCode")]
+	if !ok {
+		invalid(w, fieldError{Key: "ProductCode", Msg: "Unknown ProductCode"})
+		return
+	}
+	sans, _ := strconv.Atoi(q.Get("SubjectAltNameCount"))
+	validity, _ := strconv.Atoi(q.Get("ValidityPeriod"))
+	servers, _ := strconv.Atoi(q.Get("ServerCount"))
+	if validity == 0 {
+		validity = 12
+	}
+	price, ok := s.price(p, sans, validity, servers)
+	if !ok {
+		invalid(w, fieldError{Key: "ValidityPeriod", Msg: "Invalid ValidityPeriod"})
+		return
+	}
+	res := certcenter.QuoteResult{
+		BasicResultInfo: success(),
+		Currency:        p.Currency,
+		Price:           price,
+	}
+	res.OrderParameters.ProductCode = p.ProductCode
+	res.OrderParameters.SubjectAltNameCount = sans
+	res.OrderParameters.ValidityPeriod = validity
+	res.OrderParameters.ServerCount = servers
+	writeJSON(w, http.StatusOK, res)
+}
+
+// parseCSR decodes a PEM-encoded PKCS#10 the way the client does (see
+// certcenter.ParseCSR) and checks its signature
+func parseCSR(csr string) (*certcenter.CSRDetails, error) {
+	d, err := certcenter.ParseCSR(csr)
+	if err != nil {
+		return nil, err
+	}
+	return d, d.Request.CheckSignature()
+}
+
+func (s *Server) validateCSR(w http.ResponseWriter, r *request) {
+	var in certcenter.ValidateCSRRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	csr, err := parseCSR(in.CSR)
+	if err != nil {
+		invalid(w, fieldError{Key: "CSR", Msg: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, certcenter.ValidateCSRResult{
+		BasicResultInfo: success(),
+		ParsedCSR:       csr.ParsedCSR,
+	})
+}
+
+// baseDomain strips wildcard labels and subdomains from name
+func baseDomain(name string) string {
+	labels := strings.Split(strings.TrimPrefix(name, "*."), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
+
+func (s *Server) approverList(w http.ResponseWriter, r *request) {
+	q := r.URL.Query()
+	names := []string{q.Get("CommonName")}
+	if dnsNames := q.Get("DNSNames"); dnsNames != "" {
+		names = append(names, strings.Split(dnsNames, ",")...)
+	}
+	res := certcenter.ApproverListResult{
+		BasicResultInfo: success(),
+		DomainApprovers: &certcenter.DomainApprovers{},
+	}
+	for i, name := range names {
+		domain := baseDomain(strings.TrimSpace(name))
+		item := certcenter.DomainApproverItem{Domain: domain}
+		for _, mailbox := range []string{"admin", "administrator", "hostmaster", "postmaster", "webmaster"} {
+			item.Approvers = append(item.Approvers, certcenter.Approver{
+				ApproverEmail: mailbox + "@" + domain,
+				ApproverType:  "Generic",
+			})
+		}
+		if i == 0 {
+			res.ApproverList = item.Approvers
+		}
+		res.DomainApprovers.DomainApprover = append(res.DomainApprovers.DomainApprover, item)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// orderInput holds the parts of an Order (or RedeemVoucher) request
+type orderInput struct {
+	OrganizationInfo *certcenter.OrganizationInfo
+	OrderParameters  *certcenter.OrderParameters
+	AdminContact     *certcenter.Contact
+	TechContact      *certcenter.Contact
+}
+
+// placeOrder validates in and stores a new order, charging its price
+// less what has been prepaid (eg. by a voucher). It writes an error
+// response and returns nil if the order cannot be placed.
+func (s *Server) placeOrder(w http.ResponseWriter, in orderInput, prepaid float64) *order {
+	params := in.OrderParameters
+	if params == nil {
+		invalid(w, fieldError{Key: "OrderParameters", Msg: "OrderParameters are required"})
+		return nil
+	}
+	p, ok := s.products[params.ProductCode]
+	if !ok {
+		invalid(w, fieldError{Key: "OrderParameters.ProductCode", Msg: "Unknown ProductCode"})
+		return nil
+	}
+	csr, err := parseCSR(params.CSR)
+	if err != nil {
+		invalid(w, fieldError{Key: "OrderParameters.CSR", Msg: err.Error()})
+		return nil
+	}
+	price, priced := s.price(p, sanCount(params), params.ValidityPeriod, params.ServerCount)
+	if params.ValidityPeriod <= 0 || params.ValidityPeriod > p.MaxValidityPeriod || !priced {
+		invalid(w, fieldError{Key: "OrderParameters.ValidityPeriod", Msg: "Invalid ValidityPeriod"})
+		return nil
+	}
+	if (hasFeature(p, "OV") || hasFeature(p, "EV")) && (in.OrganizationInfo == nil || in.AdminContact == nil) {
+		invalid(w, fieldError{Key: "OrganizationInfo", Msg: "OrganizationInfo and AdminContact are required"})
+		return nil
+	}
+
+	charge := price - prepaid
+	if charge < 0 {
+		charge = 0
+	}
+	if s.used+charge > s.Limit {
+		writeError(w, http.StatusPaymentRequired, -2010, "", "Insufficient limit")
+		return nil
+	}
+	s.used += charge
+
+	s.nextID++
+	now := s.now()
+	o := &order{price: charge}
+	info := &o.info
+	info.CertCenterOrderID = s.nextID
+	info.CommonName = csr.CommonName
+	info.OrderStatus = certcenter.OrderStatus{
+		MajorStatus: certcenter.StatusPending,
+		MinorStatus: "WAIT_FOR_APPROVAL",
+		OrderDate:   now,
+		UpdateDate:  now,
+		Progress:    10,
+	}
+	info.BillingInfo = certcenter.BillingInfo{
+		Price:    float32(price),
+		Currency: p.Currency,
+		Status:   "uncleared",
+	}
+	info.OrderParameters = *params
+	info.Fulfillment.CSR = params.CSR
+	if in.OrganizationInfo != nil {
+		info.OrganizationInfo = *in.OrganizationInfo
+	}
+	if in.AdminContact != nil {
+		info.ContactInfo.AdminContact = *in.AdminContact
+	}
+	if in.TechContact != nil {
+		info.ContactInfo.TechContact = *in.TechContact
+	}
+	for _, domain := range append([]string{info.CommonName}, params.SubjectAltNames...) {
+		info.DCVStatus = append(info.DCVStatus, certcenter.DCVStatus{
+			DomainControlValidationID: int32(len(info.DCVStatus) + 1),
+			Domain:                    domain,
+			Status:                    "PENDING",
+			LastUpdateDate:            now,
+		})
+	}
+	s.orders[info.CertCenterOrderID] = o
+
+	if hasFeature(p, "AlwaysOnSSL") {
+		if err := s.complete(o); err != nil {
+			delete(s.orders, info.CertCenterOrderID)
+			writeError(w, http.StatusInternalServerError, 0, "", err.Error())
+			return nil
+		}
+	}
+	return o
+}
+
+func hasFeature(p certcenter.ProductInfo, feature string) bool {
+	for _, f := range p.Features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) orderResult(o *order) certcenter.OrderResult {
+	res := certcenter.OrderResult{
+		BasicResultInfo:   success(),
+		Timestamp:         o.info.OrderStatus.OrderDate,
+		CertCenterOrderID: o.info.CertCenterOrderID,
+	}
+	params := o.info.OrderParameters
+	res.OrderParameters.CSR = params.CSR
+	res.OrderParameters.IsCompetitiveUpgrade = params.IsCompetitiveUpgrade
+	res.OrderParameters.IsRenewal = params.IsRenewal
+	res.OrderParameters.PartnerOrderID = params.PartnerOrderID
+	res.OrderParameters.ProductCode = params.ProductCode
+	res.OrderParameters.ServerCount = params.ServerCount
+	res.OrderParameters.SignatureHashAlgorithm = params.SignatureHashAlgorithm
+	res.OrderParameters.SubjectAltNameCount = params.SubjectAltNameCount
+	res.OrderParameters.SubjectAltNames = params.SubjectAltNames
+	res.OrderParameters.ValidityPeriod = params.ValidityPeriod
+	res.OrderParameters.DVAuthMethod = params.DVAuthMethod
+	if o.info.OrderStatus.MajorStatus == certcenter.StatusComplete {
+		res.Fulfillment.Certificate = o.info.Fulfillment.Certificate
+		res.Fulfillment.Intermediate = o.info.Fulfillment.Intermediate
+		res.Fulfillment.PKCS7 = o.pkcs7
+	}
+	return res
+}
+
+func (s *Server) order(w http.ResponseWriter, r *request) {
+	var in certcenter.OrderRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	o := s.placeOrder(w, orderInput{
+		OrganizationInfo: in.OrganizationInfo,
+		OrderParameters:  in.OrderParameters,
+		AdminContact:     in.AdminContact,
+		TechContact:      in.TechContact,
+	}, 0)
+	if o != nil {
+		writeJSON(w, http.StatusOK, s.orderResult(o))
+	}
+}
+
+// view returns the order information as selected by the include* flags
+func (o *order) view(q url.Values) certcenter.OrderInfo {
+	info := o.info
+	if !flag(q, "includeFulfillment") {
+		info.Fulfillment = certcenter.Fulfillment{}
+	}
+	if !flag(q, "includeOrderParameters") {
+		info.OrderParameters = certcenter.OrderParameters{}
+		info.DNSAuthDetails = certcenter.DNSAuthDetails{}
+		info.FileAuthDetails = certcenter.FileAuthDetails{}
+		info.EmailAuthDetails = certcenter.EmailAuthDetails{}
+	}
+	if !flag(q, "includeBillingDetails") {
+		info.BillingInfo = certcenter.BillingInfo{}
+	}
+	if !flag(q, "includeContacts") {
+		info.ContactInfo = certcenter.ContactInfoPair{}
+	}
+	if !flag(q, "includeOrganizationInfos") {
+		info.OrganizationInfo = certcenter.OrganizationInfo{}
+	}
+	if !flag(q, "includeDCVStatus") {
+		info.DCVStatus = nil
+	}
+	return info
+}
+
+// productType derives the ProductType filter value of a product
+func productType(p certcenter.ProductInfo) certcenter.ProductType {
+	if hasFeature(p, "SMIME") {
+		return certcenter.ProductTypeSMIME
+	}
+	if hasFeature(p, "CODESIGN") {
+		return certcenter.ProductTypeCodeSign
+	}
+	return certcenter.ProductTypeSSL
+}
+
+// sortedOrders returns all orders, latest first
+func (s *Server) sortedOrders() []*order {
+	orders := make([]*order, 0, len(s.orders))
+	for _, o := range s.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].info.CertCenterOrderID > orders[j].info.CertCenterOrderID
+	})
+	return orders
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) getOrders(w http.ResponseWriter, r *request) {
+	q := r.URL.Query()
+	status := splitList(q.Get("Status"))
+	types := splitList(q.Get("ProductType"))
+	commonName := strings.ToLower(q.Get("CommonName"))
+	var ranges [4]time.Time
+	for i, key := range []string{"OrderDateFrom", "OrderDateTo", "ExpirationDateFrom", "ExpirationDateTo"} {
+		if v := q.Get(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				invalid(w, fieldError{Key: key, Msg: err.Error()})
+				return
+			}
+			ranges[i] = t
+		}
+	}
+	page, perPage := int64(1), int64(DefaultItemsPerPage)
+	if v := q.Get("Page"); v != "" {
+		page, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := q.Get("ItemsPerPage"); v != "" {
+		perPage, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if page < 1 {
+		invalid(w, fieldError{Key: "Page", Msg: "Must be a positive number"})
+		return
+	}
+	if perPage < 1 || perPage > MaxItemsPerPage {
+		invalid(w, fieldError{Key: "ItemsPerPage", Msg: fmt.Sprintf("Must be between 1 and %d", MaxItemsPerPage)})
+		return
+	}
+	orderBy := certcenter.OrderBy(q.Get("OrderBy"))
+	orderDir := certcenter.OrderDir(strings.ToUpper(q.Get("OrderDir")))
+	if orderBy == "" {
+		orderBy = certcenter.OrderByID
+	}
+	if orderDir == "" {
+		orderDir = certcenter.OrderDescending
+	}
+	less, ok := orderings[orderBy]
+	if !ok {
+		invalid(w, fieldError{Key: "OrderBy", Msg: "Unsupported order"})
+		return
+	}
+	if !orderDir.IsValid() {
+		invalid(w, fieldError{Key: "OrderDir", Msg: "Must be ASC or DESC"})
+		return
+	}
+
+	var matches []*order
+	for _, o := range s.sortedOrders() {
+		if len(status) > 0 && !contains(status, string(o.info.OrderStatus.MajorStatus)) {
+			continue
+		}
+		if len(types) > 0 && !contains(types, string(productType(s.products[o.info.OrderParameters.ProductCode]))) {
+			continue
+		}
+		if commonName != "" && !like(strings.ToLower(o.info.CommonName), commonName) {
+			continue
+		}
+		if !within(o.info.OrderStatus.OrderDate, ranges[0], ranges[1]) ||
+			!within(o.info.OrderStatus.EndDate, ranges[2], ranges[3]) {
+			continue
+		}
+		matches = append(matches, o)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if orderDir == certcenter.OrderAscending {
+			return less(matches[i], matches[j])
+		}
+		return less(matches[j], matches[i])
+	})
+
+	res := certcenter.GetOrdersResult{BasicResultInfo: success()}
+	for i := (page - 1) * perPage; i < page*perPage && i < int64(len(matches)); i++ {
+		res.OrderInfos = append(res.OrderInfos, matches[i].view(q))
+	}
+	res.Meta.ItemsAvailable = int64(len(matches))
+	res.Meta.ItemsPerPage = perPage
+	res.Meta.Page = page
+	res.Meta.OrderBy = orderBy
+	res.Meta.OrderDir = orderDir
+	for _, v := range status {
+		res.Meta.Status = append(res.Meta.Status, certcenter.MajorStatus(v))
+	}
+	for _, v := range types {
+		res.Meta.ProductType = append(res.Meta.ProductType, certcenter.ProductType(v))
+	}
+	res.Meta.CommonName = q.Get("CommonName")
+	writeJSON(w, http.StatusOK, res)
+}
+
+const (
+	// DefaultItemsPerPage is the page size of GET /Orders if none is requested
+	DefaultItemsPerPage = 100
+	// MaxItemsPerPage is the largest page size GET /Orders accepts
+	MaxItemsPerPage = 1000
+)
+
+// orderings are the supported OrderBy values of GET /Orders
+var orderings = map[certcenter.OrderBy]func(a, b *order) bool{
+	certcenter.OrderByID: func(a, b *order) bool {
+		return a.info.CertCenterOrderID < b.info.CertCenterOrderID
+	},
+	certcenter.OrderByOrderDate: func(a, b *order) bool {
+		return a.info.OrderStatus.OrderDate.Before(b.info.OrderStatus.OrderDate)
+	},
+	certcenter.OrderByUpdateDate: func(a, b *order) bool {
+		return a.info.OrderStatus.UpdateDate.Before(b.info.OrderStatus.UpdateDate)
+	},
+	certcenter.OrderByExpirationDate: func(a, b *order) bool {
+		return a.info.OrderStatus.EndDate.Before(b.info.OrderStatus.EndDate)
+	},
+	certcenter.OrderByCommonName: func(a, b *order) bool {
+		return a.info.CommonName < b.info.CommonName
+	},
+}
+
+// like reports whether s contains pattern, where % in pattern matches
+// any sequence of characters
+func like(s, pattern string) bool {
+	for _, part := range strings.Split(pattern, "%") {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return true
+}
+
+// within reports whether t lies in [from, to]; zero bounds are open.
+// Orders without a date (eg. pending ones have no EndDate) never match
+// a bounded range.
+func within(t, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+func (s *Server) getModifiedOrders(w http.ResponseWriter, r *request) {
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("FromDate"))
+	if err != nil {
+		invalid(w, fieldError{Key: "FromDate", Msg: err.Error()})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("ToDate"))
+	if err != nil {
+		invalid(w, fieldError{Key: "ToDate", Msg: err.Error()})
+		return
+	}
+	res := certcenter.GetModifiedOrdersResult{BasicResultInfo: success()}
+	for _, o := range s.sortedOrders() {
+		updated := o.info.OrderStatus.UpdateDate
+		if updated.Before(from) || updated.After(to) {
+			continue
+		}
+		res.OrderInfos = append(res.OrderInfos, o.view(q))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// lookup returns the order identified by the path parameter
+func (s *Server) lookup(w http.ResponseWriter, param string) *order {
+	id, _ := strconv.ParseInt(param, 10, 64)
+	o, ok := s.orders[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, -1001, "CertCenterOrderID", "Order not found")
+		return nil
+	}
+	return o
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *request) {
+	o := s.lookup(w, r.param)
+	if o == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, certcenter.GetOrderResult{
+		BasicResultInfo: success(),
+		OrderInfo:       o.view(r.URL.Query()),
+	})
+}
+
+func (s *Server) deleteOrder(w http.ResponseWriter, r *request) {
+	o := s.lookup(w, r.param)
+	if o == nil {
+		return
+	}
+	if o.info.OrderStatus.MajorStatus == certcenter.StatusComplete {
+		invalid(w, fieldError{Key: "CertCenterOrderID", Msg: "Completed orders cannot be cancelled"})
+		return
+	}
+	s.setStatus(o, certcenter.StatusCancelled, "", o.info.OrderStatus.Progress)
+	s.used -= o.price
+	writeJSON(w, http.StatusOK, certcenter.DeleteOrderResult{BasicResultInfo: success()})
+}
+
+func (s *Server) reissue(w http.ResponseWriter, r *request) {
+	var in certcenter.ReissueRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	o := s.lookup(w, strconv.FormatInt(in.CertCenterOrderID, 10))
+	if o == nil {
+		return
+	}
+	if o.info.OrderStatus.MajorStatus != certcenter.StatusComplete {
+		invalid(w, fieldError{Key: "CertCenterOrderID", Msg: "Only completed orders can be reissued"})
+		return
+	}
+	csr, err := parseCSR(in.OrderParameters.CSR)
+	if err != nil {
+		invalid(w, fieldError{Key: "OrderParameters.CSR", Msg: err.Error()})
+		return
+	}
+	// further SANs requested by the CSR are charged
+	params := &o.info.OrderParameters
+	if _, sans := csr.MismatchedNames(nil); len(sans) > sanCount(params) {
+		p := s.products[params.ProductCode]
+		before, _ := s.price(p, sanCount(params), params.ValidityPeriod, params.ServerCount)
+		after, priced := s.price(p, len(sans), params.ValidityPeriod, params.ServerCount)
+		if len(sans) > p.SANMaxHosts || !priced {
+			invalid(w, fieldError{Key: "OrderParameters.CSR", Msg: "Too many SubjectAltNames"})
+			return
+		}
+		if s.used+after-before > s.Limit {
+			writeError(w, http.StatusPaymentRequired, -2010, "", "Insufficient limit")
+			return
+		}
+		s.used += after - before
+		o.price += after - before
+		params.SubjectAltNames = sans
+		params.SubjectAltNameCount = len(sans)
+	}
+	o.info.OrderParameters.CSR = in.OrderParameters.CSR
+	o.info.Fulfillment.CSR = in.OrderParameters.CSR
+	if in.OrderParameters.SignatureHashAlgorithm != "" {
+		o.info.OrderParameters.SignatureHashAlgorithm = in.OrderParameters.SignatureHashAlgorithm
+	}
+	if in.OrderParameters.DVAuthMethod != "" {
+		o.info.OrderParameters.DVAuthMethod = in.OrderParameters.DVAuthMethod
+	}
+	if hasFeature(s.products[o.info.OrderParameters.ProductCode], "AlwaysOnSSL") {
+		if err := s.complete(o); err != nil {
+			writeError(w, http.StatusInternalServerError, 0, "", err.Error())
+			return
+		}
+	} else {
+		s.setStatus(o, certcenter.StatusPending, "REISSUE", 10)
+	}
+	writeJSON(w, http.StatusOK, certcenter.ReissueResult{BasicResultInfo: success()})
+}
+
+func (s *Server) revoke(w http.ResponseWriter, r *request) {
+	var in certcenter.RevokeRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	o := s.lookup(w, r.param)
+	if o == nil {
+		return
+	}
+	if o.info.OrderStatus.MajorStatus != certcenter.StatusComplete {
+		invalid(w, fieldError{Key: "CertCenterOrderID", Msg: "Only completed orders can be revoked"})
+		return
+	}
+	if o.cert != nil {
+		s.CA.Revoke(o.cert, string(in.RevokeReason), s.now())
+	}
+	s.setStatus(o, certcenter.StatusRevoked, string(in.RevokeReason), 100)
+	writeJSON(w, http.StatusOK, certcenter.RevokeResult{BasicResultInfo: success()})
+}
+
+func (s *Server) validateName(w http.ResponseWriter, r *request) {
+	var in certcenter.ValidateNameRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	res := certcenter.ValidateNameResult{BasicResultInfo: success(), IsQualified: true}
+	for _, word := range s.NameBlacklist {
+		if strings.Contains(strings.ToLower(in.CommonName), strings.ToLower(word)) {
+			res.IsQualified = false
+		}
+	}
+	if in.GeneratePrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, 0, "", err.Error())
+			return
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: in.CommonName},
+			DNSNames: []string{in.CommonName},
+		}, key)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, 0, "", err.Error())
+			return
+		}
+		res.CSR = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+		res.PrivateKey = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// dcvInput decodes the CSR of a DNSData or FileData request
+func dcvInput(w http.ResponseWriter, r *request) (*certcenter.CSRDetails, bool) {
+	var in certcenter.DNSDataRequest
+	if !decode(w, r, &in) {
+		return nil, false
+	}
+	csr, err := parseCSR(in.CSR)
+	if err != nil {
+		invalid(w, fieldError{Key: "CSR", Msg: err.Error()})
+		return nil, false
+	}
+	return csr, true
+}
+
+func (s *Server) dnsData(w http.ResponseWriter, r *request) {
+	csr, ok := dcvInput(w, r)
+	if !ok {
+		return
+	}
+	res := certcenter.DNSDataResult{BasicResultInfo: success()}
+	d := &res.DNSAuthDetails
+	d.PointerType = "CNAME"
+	d.DNSEntry = "_" + strings.ToLower(csr.HashMD5) + "." + strings.TrimPrefix(csr.CommonName, "*.")
+	d.DNSValue = strings.ToLower(csr.HashSHA256[:32]+"."+csr.HashSHA256[32:]) + ".certcentertest.invalid"
+	d.Example = d.DNSEntry + ". 3600 IN CNAME " + d.DNSValue + "."
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) fileData(w http.ResponseWriter, r *request) {
+	csr, ok := dcvInput(w, r)
+	if !ok {
+		return
+	}
+	res := certcenter.FileDataResult{BasicResultInfo: success()}
+	f := &res.FileAuthDetails
+	f.FileName = csr.HashMD5 + ".txt"
+	f.FilePath = "/.well-known/pki-validation/"
+	f.FileContents = csr.HashSHA256 + "\ncertcentertest.invalid"
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *request) {
+	var in certcenter.CreateUserRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Username == "" {
+		invalid(w, fieldError{Key: "Username", Msg: "Username is required"})
+		return
+	}
+	if _, exists := s.users[in.Username]; exists {
+		invalid(w, fieldError{Key: "Username", Msg: "Username already taken"})
+		return
+	}
+	s.nextUser++
+	user := in.UserData
+	user.UsernameOrUserId = strconv.FormatInt(s.nextUser, 10)
+	user.Password = ""
+	user.Active = true
+	user.InsertDate = s.now().Unix()
+	user.LastUpdateDate = user.InsertDate
+	s.users[user.Username] = &user
+	writeJSON(w, http.StatusOK, certcenter.CreateUserResult{
+		BasicResultInfo: success(),
+		Id:              s.nextUser,
+		FullName:        user.FullName,
+		Username:        user.Username,
+		Roles:           user.Roles,
+	})
+}
+
+// findUser looks up a user by name or id
+func (s *Server) findUser(w http.ResponseWriter, usernameOrUserID string) *certcenter.UserData {
+	for _, user := range s.users {
+		if user.Username == usernameOrUserID || user.UsernameOrUserId == usernameOrUserID {
+			return user
+		}
+	}
+	writeError(w, http.StatusNotFound, -1002, "UsernameOrUserId", "User not found")
+	return nil
+}
+
+func userID(user *certcenter.UserData) int64 {
+	id, _ := strconv.ParseInt(user.UsernameOrUserId, 10, 64)
+	return id
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *request) {
+	var in certcenter.UpdateUserRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	user := s.findUser(w, r.param)
+	if user == nil {
+		return
+	}
+	if in.FullName != "" {
+		user.FullName = in.FullName
+	}
+	if in.Email != "" {
+		user.Email = in.Email
+	}
+	if in.Roles != nil {
+		user.Roles = in.Roles
+	}
+	if in.Mobile != "" {
+		user.Mobile = in.Mobile
+	}
+	if in.Timezone != "" {
+		user.Timezone = in.Timezone
+	}
+	if in.Locale != "" {
+		user.Locale = in.Locale
+	}
+	if in.Password != "" {
+		user.LastPasswordChangeDate = s.now().Unix()
+	}
+	user.LastUpdateDate = s.now().Unix()
+	writeJSON(w, http.StatusOK, certcenter.UpdateUserResult{BasicResultInfo: success(), Id: userID(user)})
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *request) {
+	type userResult struct {
+		certcenter.BasicResultInfo
+		Id int64
+		certcenter.UserData
+	}
+	if r.param != "" {
+		user := s.findUser(w, r.param)
+		if user != nil {
+			writeJSON(w, http.StatusOK, userResult{success(), userID(user), *user})
+		}
+		return
+	}
+	res := struct {
+		certcenter.BasicResultInfo
+		Users []certcenter.UserData
+	}{BasicResultInfo: success()}
+	for _, user := range s.users {
+		res.Users = append(res.Users, *user)
+	}
+	sort.Slice(res.Users, func(i, j int) bool { return res.Users[i].UsernameOrUserId < res.Users[j].UsernameOrUserId })
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *request) {
+	user := s.findUser(w, r.param)
+	if user == nil {
+		return
+	}
+	delete(s.users, user.Username)
+	writeJSON(w, http.StatusOK, certcenter.DeleteUserResult{BasicResultInfo: success(), Id: userID(user)})
+}
+
+func (s *Server) createVoucher(w http.ResponseWriter, r *request) {
+	var in certcenter.CreateVoucherRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	p, ok := s.products[in.OrderParameters.ProductCode]
+	if !ok {
+		invalid(w, fieldError{Key: "OrderParameters.ProductCode", Msg: "Unknown ProductCode"})
+		return
+	}
+	price, ok := s.price(p, sanCount(&in.OrderParameters), in.OrderParameters.ValidityPeriod, in.OrderParameters.ServerCount)
+	if !ok {
+		invalid(w, fieldError{Key: "OrderParameters.ValidityPeriod", Msg: "Invalid ValidityPeriod"})
+		return
+	}
+	if s.used+price > s.Limit {
+		writeError(w, http.StatusPaymentRequired, -2010, "", "Insufficient limit")
+		return
+	}
+	s.used += price
+	v := &voucher{
+		CreationDate:    s.now(),
+		OrderParameters: in.OrderParameters,
+		VoucherCode:     newVoucherCode(),
+	}
+	s.vouchers[v.VoucherCode] = v
+	writeJSON(w, http.StatusOK, certcenter.CreateVoucherResult{
+		BasicResultInfo: success(),
+		VoucherCode:     v.VoucherCode,
+		OrderParameters: v.OrderParameters,
+	})
+}
+
+func newVoucherCode() string {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	code := make([]byte, 12)
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		code[i] = alphabet[n.Int64()]
+	}
+	return "CC" + string(code)
+}
+
+func (s *Server) redeemVoucher(w http.ResponseWriter, r *request) {
+	var in certcenter.RedeemVoucherRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	v, ok := s.vouchers[in.VoucherCode]
+	if !ok || v.Redeemed {
+		invalid(w, fieldError{Key: "VoucherCode", Msg: "Invalid or already redeemed VoucherCode"})
+		return
+	}
+	params := certcenter.OrderParameters{}
+	if in.OrderParameters != nil {
+		params = *in.OrderParameters
+	}
+	// further SANs and servers are charged on top of the voucher
+	paid := v.OrderParameters
+	prepaid, _ := s.price(s.products[paid.ProductCode], sanCount(&paid), paid.ValidityPeriod, paid.ServerCount)
+	params.ProductCode = paid.ProductCode
+	params.ValidityPeriod = paid.ValidityPeriod
+	if params.SubjectAltNameCount < paid.SubjectAltNameCount {
+		params.SubjectAltNameCount = paid.SubjectAltNameCount
+	}
+	if params.ServerCount < paid.ServerCount {
+		params.ServerCount = paid.ServerCount
+	}
+	o := s.placeOrder(w, orderInput{
+		OrganizationInfo: in.OrganizationInfo,
+		OrderParameters:  &params,
+		AdminContact:     in.AdminContact,
+		TechContact:      in.TechContact,
+	}, prepaid)
+	if o == nil {
+		return
+	}
+	v.Redeemed = true
+	v.RedeemInfo.RedeemDate = s.now()
+	v.RedeemInfo.CertCenterOrderID = o.info.CertCenterOrderID
+	writeJSON(w, http.StatusOK, certcenter.RedeemVoucherResult{OrderResult: s.orderResult(o)})
+}
+
+func (s *Server) getVouchers(w http.ResponseWriter, r *request) {
+	res := struct {
+		certcenter.BasicResultInfo
+		Vouchers []voucher
+	}{BasicResultInfo: success(), Vouchers: []voucher{}}
+	if r.param != "" {
+		v, ok := s.vouchers[r.param]
+		if !ok {
+			writeError(w, http.StatusNotFound, -1003, "VoucherCode", "Voucher not found")
+			return
+		}
+		res.Vouchers = append(res.Vouchers, *v)
+	} else {
+		for _, v := range s.vouchers {
+			res.Vouchers = append(res.Vouchers, *v)
+		}
+		sort.Slice(res.Vouchers, func(i, j int) bool { return res.Vouchers[i].VoucherCode < res.Vouchers[j].VoucherCode })
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// getVoucherOrder reports a redeemed voucher along with the
+// OrderParameters of the order it has been redeemed for
+func (s *Server) getVoucherOrder(w http.ResponseWriter, r *request) {
+	v, ok := s.vouchers[r.param]
+	if !ok || !v.Redeemed {
+		writeError(w, http.StatusNotFound, -1003, "VoucherCode", "Voucher not found or not redeemed yet")
+		return
+	}
+	redeemed := *v
+	if o, ok := s.orders[v.RedeemInfo.CertCenterOrderID]; ok {
+		redeemed.OrderParameters = o.info.OrderParameters
+	}
+	writeJSON(w, http.StatusOK, struct {
+		certcenter.BasicResultInfo
+		Vouchers []voucher
+	}{success(), []voucher{redeemed}})
+}
+
+func (s *Server) deleteVoucher(w http.ResponseWriter, r *request) {
+	v, ok := s.vouchers[r.param]
+	if !ok || v.Redeemed {
+		writeError(w, http.StatusNotFound, -1003, "VoucherCode", "Voucher not found or already redeemed")
+		return
+	}
+	delete(s.vouchers, v.VoucherCode)
+	writeJSON(w, http.StatusOK, certcenter.DeleteVoucherResult{OrderResult: certcenter.OrderResult{BasicResultInfo: success()}})
+}
+
+func (s *Server) resendApproverEmail(w http.ResponseWriter, r *request) {
+	o := s.lookup(w, r.param)
+	if o == nil {
+		return
+	}
+	o.info.EmailAuthDetails.ApproverNotifyDate = s.now()
+	writeJSON(w, http.StatusOK, certcenter.ResendApproverEmailResult{BasicResultInfo: success()})
+}
+
+func (s *Server) putApproverEmail(w http.ResponseWriter, r *request) {
+	o := s.lookup(w, r.param)
+	if o == nil {
+		return
+	}
+	o.info.EmailAuthDetails.ApproverEmail = r.URL.Query().Get("ApproverEmail")
+	o.info.EmailAuthDetails.ApproverNotifyDate = s.now()
+	o.info.OrderStatus.UpdateDate = s.now()
+	writeJSON(w, http.StatusOK, certcenter.PutApproverEmailResult{BasicResultInfo: success()})
+}
+
+func (s *Server) vulnerabilityAssessmentRescan(w http.ResponseWriter, r *request) {
+	if s.lookup(w, r.param) == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, certcenter.VulnerabilityAssessmentRescanResult{BasicResultInfo: success()})
+}
+
+func (s *Server) kvStore(w http.ResponseWriter, r *http.Request, key string) {
+	var in certcenter.KeyValueStoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || key == "" {
+		writeJSON(w, http.StatusBadRequest, certcenter.KeyValueStoreResult{Message: "Invalid request"})
+		return
+	}
+	s.mu.Lock()
+	s.kv[key] = in.Value
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, certcenter.KeyValueStoreResult{Message: fmt.Sprintf("Stored %s", key)})
+}
@@ -0,0 +1,143 @@
+package certcentertest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	certcenter "certcenter.com/go"
+)
+
+// Order returns the stored information about an order, including
+// all optional parts (fulfillment, contacts, ..)
+func (s *Server) Order(id int64) (certcenter.OrderInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return certcenter.OrderInfo{}, false
+	}
+	return o.info, true
+}
+
+// CompleteOrder finishes domain control validation of a pending
+// order and issues the certificate
+func (s *Server) CompleteOrder(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return fmt.Errorf("certcentertest: unknown order %d", id)
+	}
+	if o.info.OrderStatus.MajorStatus != certcenter.StatusPending {
+		return fmt.Errorf("certcentertest: order %d is %s", id, o.info.OrderStatus.MajorStatus)
+	}
+	return s.complete(o)
+}
+
+// SetOrderStatus changes the status of an order, eg. to simulate
+// progress of the validation
+func (s *Server) SetOrderStatus(id int64, majorStatus certcenter.MajorStatus, minorStatus string, progress int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return fmt.Errorf("certcentertest: unknown order %d", id)
+	}
+	s.setStatus(o, majorStatus, minorStatus, progress)
+	return nil
+}
+
+// KvValue returns the value stored in the kv-storage for key
+func (s *Server) KvValue(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.kv[key]
+	return value, ok
+}
+
+func (s *Server) setStatus(o *order, majorStatus certcenter.MajorStatus, minorStatus string, progress int) {
+	o.info.OrderStatus.MajorStatus = majorStatus
+	o.info.OrderStatus.MinorStatus = minorStatus
+	o.info.OrderStatus.Progress = progress
+	o.info.OrderStatus.UpdateDate = s.now()
+}
+
+// complete issues the certificate of o and marks it as COMPLETE
+func (s *Server) complete(o *order) error {
+	csr, err := parseCSR(o.info.OrderParameters.CSR)
+	if err != nil {
+		return err
+	}
+	product := s.products[o.info.OrderParameters.ProductCode]
+	now := s.now()
+	end := now.AddDate(0, o.info.OrderParameters.ValidityPeriod, 0)
+	if hasFeature(product, "AlwaysOnSSL") {
+		end = now.AddDate(0, 0, o.info.OrderParameters.ValidityPeriod)
+	}
+	cert, err := s.CA.Issue(IssueRequest{
+		CSR:                    csr.Request,
+		SubjectAltNames:        o.info.OrderParameters.SubjectAltNames,
+		NotBefore:              now,
+		NotAfter:               end,
+		SignatureHashAlgorithm: string(o.info.OrderParameters.SignatureHashAlgorithm),
+		Organization:           o.info.OrganizationInfo.OrganizationName,
+		SMIME:                  productType(product) == certcenter.ProductTypeSMIME,
+	})
+	if err != nil {
+		return err
+	}
+	pkcs7, err := encodePKCS7(cert, s.CA.Intermediate)
+	if err != nil {
+		return err
+	}
+	o.cert = cert
+	o.pkcs7 = pkcs7
+
+	id := strconv.FormatInt(o.info.CertCenterOrderID, 10)
+	f := &o.info.Fulfillment
+	f.StartDate = cert.NotBefore
+	f.EndDate = cert.NotAfter
+	f.Certificate = encodeCertificates(cert)
+	f.Intermediate = encodeCertificates(s.CA.Intermediate)
+	f.DownloadLinks.Certificate = s.URL + DownloadPath + id + "/certificate.pem"
+	f.DownloadLinks.Intermediate = s.URL + DownloadPath + id + "/intermediate.pem"
+	f.DownloadLinks.PKCS7 = s.URL + DownloadPath + id + "/certificate.p7b"
+	o.info.OrderStatus.StartDate = cert.NotBefore
+	o.info.OrderStatus.EndDate = cert.NotAfter
+	for i := range o.info.DCVStatus {
+		o.info.DCVStatus[i].Status = "VALID"
+		o.info.DCVStatus[i].LastCheckDate = now
+		o.info.DCVStatus[i].LastUpdateDate = now
+	}
+	s.setStatus(o, certcenter.StatusComplete, "", 100)
+	return nil
+}
+
+// download serves the fulfillment download links
+func (s *Server) download(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.SplitN(path, "/", 2)
+	id, _ := strconv.ParseInt(parts[0], 10, 64)
+	s.mu.Lock()
+	o, ok := s.orders[id]
+	var body string
+	if ok && o.cert != nil && len(parts) == 2 {
+		switch parts[1] {
+		case "certificate.pem":
+			body = o.info.Fulfillment.Certificate
+		case "intermediate.pem":
+			body = o.info.Fulfillment.Intermediate
+		case "certificate.p7b":
+			body = o.pkcs7
+		}
+	}
+	s.mu.Unlock()
+	if body == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	io.WriteString(w, body)
+}
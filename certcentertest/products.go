@@ -0,0 +1,170 @@
+package certcentertest
+
+import (
+	certcenter "certcenter.com/go"
+)
+
+// DefaultProducts is the product catalog a new Server starts with
+var DefaultProducts = []certcenter.ProductInfo{
+	{
+		ProductCode:       "Comodo.PositiveSSL",
+		ProductName:       "PositiveSSL",
+		CA:                "Comodo",
+		Currency:          "EUR",
+		Features:          []string{"DV", "IDN"},
+		Licenses:          1,
+		MaxValidityPeriod: 24,
+		Price:             19,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+	},
+	{
+		ProductCode:       "Comodo.PositiveSSLWildcard",
+		ProductName:       "PositiveSSL Wildcard",
+		CA:                "Comodo",
+		Currency:          "EUR",
+		Features:          []string{"DV", "WILDCARD", "IDN"},
+		Licenses:          1,
+		MaxValidityPeriod: 24,
+		Price:             99,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+	},
+	{
+		ProductCode:       "Comodo.PositiveSSLMDC",
+		ProductName:       "PositiveSSL Multi-Domain",
+		CA:                "Comodo",
+		Currency:          "EUR",
+		Features:          []string{"DV", "SAN", "IDN"},
+		Licenses:          1,
+		MaxValidityPeriod: 24,
+		Price:             39,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+		SANFeatures:       []string{"HostOnlySAN", "WildcardSAN"},
+		SANMaxHosts:       248,
+		SANPackagePrice:   30,
+		SANPackageSize:    1,
+	},
+	{
+		ProductCode:       "GeoTrust.QuickSSLPremium",
+		ProductName:       "QuickSSL Premium",
+		CA:                "GeoTrust",
+		Currency:          "EUR",
+		Features:          []string{"DV"},
+		Licenses:          1,
+		MaxValidityPeriod: 24,
+		Price:             79,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+		SANFeatures:       []string{"HostOnlySAN"},
+		SANHostPrice:      49,
+		SANMaxHosts:       4,
+	},
+	{
+		ProductCode:       "DigiCert.SecureSite",
+		ProductName:       "Secure Site",
+		CA:                "DigiCert",
+		Currency:          "EUR",
+		Features:          []string{"OV", "SAN", "IDN"},
+		Licenses:          0,
+		MaxValidityPeriod: 24,
+		Price:             399,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+		SANFeatures:       []string{"HostOnlySAN"},
+		SANHostPrice:      399,
+		SANMaxHosts:       24,
+	},
+	{
+		ProductCode:       "DigiCert.SecureSiteEV",
+		ProductName:       "Secure Site EV",
+		CA:                "DigiCert",
+		Currency:          "EUR",
+		Features:          []string{"EV", "SAN"},
+		Licenses:          0,
+		MaxValidityPeriod: 24,
+		Price:             995,
+		RefundPeriod:      30,
+		RenewPeriod:       90,
+		SANFeatures:       []string{"HostOnlySAN"},
+		SANHostPrice:      995,
+		SANMaxHosts:       24,
+	},
+	{
+		ProductCode:       "AlwaysOnSSL.AlwaysOnSSL",
+		ProductName:       "AlwaysOnSSL",
+		CA:                "DigiCert",
+		Currency:          "EUR",
+		Features:          []string{"DV", "AlwaysOnSSL"},
+		Licenses:          0,
+		MaxValidityPeriod: 365,
+		Price:             0,
+		SANFeatures:       []string{"HostOnlySAN"},
+		SANMaxHosts:       1,
+	},
+	{
+		ProductCode:       "Comodo.PersonalAuthentication",
+		ProductName:       "Personal Authentication",
+		CA:                "Comodo",
+		Currency:          "EUR",
+		Features:          []string{"SMIME"},
+		Licenses:          1,
+		MaxValidityPeriod: 36,
+		Price:             29,
+		RefundPeriod:      30,
+	},
+}
+
+// Price is what the fake server charges for a product and validity
+// period
+type Price struct {
+	// Base covers the certificate for the product's Licenses servers
+	Base float64
+	// SAN is charged per SubjectAltName
+	SAN float64
+	// Server is charged per server beyond the product's Licenses
+	Server float64
+}
+
+// PriceList maps a ValidityPeriod onto its Price. The Price at 0
+// applies to any period not listed.
+type PriceList map[int]Price
+
+// DefaultPrices is the price list a new Server starts with. The prices
+// are written down by hand rather than derived from DefaultProducts,
+// so certcenter.EstimatePrice can be checked against them.
+var DefaultPrices = map[string]PriceList{
+	"Comodo.PositiveSSL": {
+		12: {Base: 19, Server: 19},
+		24: {Base: 38, Server: 38},
+	},
+	"Comodo.PositiveSSLWildcard": {
+		12: {Base: 99, Server: 99},
+		24: {Base: 198, Server: 198},
+	},
+	"Comodo.PositiveSSLMDC": {
+		12: {Base: 39, SAN: 30, Server: 39},
+		24: {Base: 78, SAN: 60, Server: 78},
+	},
+	"GeoTrust.QuickSSLPremium": {
+		12: {Base: 79, SAN: 49, Server: 79},
+		24: {Base: 158, SAN: 98, Server: 158},
+	},
+	"DigiCert.SecureSite": {
+		12: {Base: 399, SAN: 399},
+		24: {Base: 798, SAN: 798},
+	},
+	"DigiCert.SecureSiteEV": {
+		12: {Base: 995, SAN: 995},
+		24: {Base: 1990, SAN: 1990},
+	},
+	"AlwaysOnSSL.AlwaysOnSSL": {
+		0: {},
+	},
+	"Comodo.PersonalAuthentication": {
+		12: {Base: 29, Server: 29},
+		24: {Base: 58, Server: 58},
+		36: {Base: 87, Server: 87},
+	},
+}
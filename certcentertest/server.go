@@ -0,0 +1,374 @@
+// Package certcentertest provides an in-process fake of the CertCenter
+// API for tests which must not depend on the network.
+//
+//	srv := certcentertest.NewServer()
+//	defer srv.Close()
+//
+//	client := srv.Client()
+//	res, err := client.Order(ctx, &certcenter.OrderRequest{..})
+//	srv.CompleteOrder(res.CertCenterOrderID)
+//
+// The fake keeps orders, vouchers, users and kv-storage entries in memory.
+// Use FailNext to script error responses for particular operations.
+package certcentertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	certcenter "certcenter.com/go"
+)
+
+const (
+	// APIPath is the path of the REST API on the fake server
+	APIPath = "/rest/v1/"
+	// KvStorePath is the path of the kv-storage on the fake server
+	KvStorePath = "/fauth/"
+	// CRLPath is the path of the CA's certificate revocation list
+	CRLPath = "/crl"
+	// DownloadPath is the path of the fulfillment download links
+	DownloadPath = "/download/"
+)
+
+// Failure describes a scripted error response (see FailNext)
+type Failure struct {
+	StatusCode int // defaults to 500
+	ErrorId    int
+	Message    string
+	// RetryAfter is sent as Retry-After header (in seconds), if set
+	RetryAfter time.Duration
+	// Delay postpones the response, eg. to provoke client timeouts
+	Delay time.Duration
+	// Drop closes the connection without any response
+	Drop bool
+	// Processed makes the server handle the request before failing,
+	// so the failure hides a successful operation (eg. a lost response)
+	Processed bool
+	// Times is the number of requests to fail (defaults to 1)
+	Times int
+}
+
+// Server is a fake CertCenter API. Configure the exported fields
+// before the first request is made.
+type Server struct {
+	*httptest.Server
+
+	// Token is the expected bearer token ("" accepts any token)
+	Token string
+	// KvStoreAuthorizationKey is the expected x-api-key of the kv-storage
+	KvStoreAuthorizationKey string
+	// Limit is reported by GET /Limit. Used grows with each order.
+	Limit float64
+	// Now is the server's clock (defaults to time.Now)
+	Now func() time.Time
+	// NameBlacklist lists substrings which make ValidateName fail
+	NameBlacklist []string
+	// CA issues the certificates of completed orders. Its CRL is
+	// published at /crl, certificates at /download/:CertCenterOrderID/
+	CA *CA
+
+	mu       sync.Mutex
+	products map[string]certcenter.ProductInfo
+	prices   map[string]PriceList
+	orders   map[int64]*order
+	nextID   int64
+	used     float64
+	vouchers map[string]*voucher
+	users    map[string]*certcenter.UserData
+	nextUser int64
+	kv       map[string]string
+	failures map[string][]Failure
+	calls    map[string]int
+	handlers map[string]handlerFunc
+}
+
+// NewServer starts and returns a new Server. Callers should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		Token:                   "certcentertest-token",
+		KvStoreAuthorizationKey: "certcentertest-kv-key",
+		Limit:                   10000,
+		Now:                     time.Now,
+		NameBlacklist:           []string{"blacklisted"},
+		products:                make(map[string]certcenter.ProductInfo),
+		prices:                  make(map[string]PriceList),
+		orders:                  make(map[int64]*order),
+		nextID:                  1000000,
+		vouchers:                make(map[string]*voucher),
+		users:                   make(map[string]*certcenter.UserData),
+		nextUser:                100,
+		kv:                      make(map[string]string),
+		failures:                make(map[string][]Failure),
+		calls:                   make(map[string]int),
+	}
+	for _, p := range DefaultProducts {
+		s.products[p.ProductCode] = p
+	}
+	for code, list := range DefaultPrices {
+		s.prices[code] = list
+	}
+	ca, err := NewCA()
+	if err != nil {
+		panic("certcentertest: failed to create CA: " + err.Error())
+	}
+	s.CA = ca
+	s.registerHandlers()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.CA.CRLURL = s.URL + CRLPath
+	return s
+}
+
+// Client returns a client talking to the fake server. Further options
+// are applied after the ones pointing the client at the server.
+func (s *Server) Client(options ...certcenter.Option) *certcenter.Client {
+	return certcenter.NewClient(append([]certcenter.Option{
+		certcenter.WithBaseURL(s.URL + APIPath),
+		certcenter.WithKvStoreURL(s.URL + KvStorePath),
+		certcenter.WithBearer(s.Token),
+		certcenter.WithKvStoreAuthorizationKey(s.KvStoreAuthorizationKey),
+	}, options...)...)
+}
+
+// FailNext makes the next request(s) of operation fail. Operation names
+// are the names of the client's methods (eg. "GetOrder", "Order") or
+// "KvStore".
+func (s *Server) FailNext(operation string, f Failure) {
+	if f.StatusCode == 0 {
+		f.StatusCode = http.StatusInternalServerError
+	}
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[operation] = append(s.failures[operation], f)
+}
+
+// Calls returns how often operation has been requested
+func (s *Server) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[operation]
+}
+
+// AddProduct adds p to (or replaces it in) the product catalog
+func (s *Server) AddProduct(p certcenter.ProductInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.products[p.ProductCode] = p
+}
+
+// SetPrices replaces the price list of a product. Products without a
+// price list are charged their ProductInfo.Price.
+func (s *Server) SetPrices(productCode string, list PriceList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[productCode] = list
+}
+
+// route maps a request onto an operation name and its path parameter
+func route(method, path string) (operation, param string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 3 && segments[1] == "*" {
+		// anonymous endpoints (eg. Voucher/*/:VoucherCode)
+		return routes[method+" "+segments[0]+"/*/"], segments[2]
+	}
+	if len(segments) > 2 {
+		return "", ""
+	}
+	if len(segments) == 2 {
+		param = segments[1]
+	}
+	key := method + " " + segments[0]
+	if param != "" {
+		key += "/"
+	}
+	return routes[key], param
+}
+
+var routes = map[string]string{
+	"GET Profile":                  "Profile",
+	"GET Limit":                    "Limit",
+	"GET Products":                 "Products",
+	"GET ProductDetails":           "ProductDetails",
+	"GET Quote":                    "Quote",
+	"POST ValidateCSR":             "ValidateCSR",
+	"GET ApproverList":             "ApproverList",
+	"POST Order":                   "Order",
+	"GET Orders":                   "GetOrders",
+	"GET GetModifiedOrders":        "GetModifiedOrders",
+	"GET Order/":                   "GetOrder",
+	"DELETE Order/":                "DeleteOrder",
+	"POST Reissue":                 "Reissue",
+	"DELETE Revoke/":               "Revoke",
+	"POST ValidateName":            "ValidateName",
+	"POST DNSData":                 "DNSData",
+	"POST FileData":                "FileData",
+	"POST User":                    "CreateUser",
+	"POST User/":                   "UpdateUser",
+	"GET User":                     "GetUser",
+	"GET User/":                    "GetUser",
+	"DELETE User/":                 "DeleteUser",
+	"POST Voucher":                 "CreateVoucher",
+	"POST Redeem":                  "RedeemVoucher",
+	"GET Vouchers":                 "GetVouchers",
+	"GET Voucher/":                 "GetVoucher",
+	"DELETE Voucher/":              "DeleteVoucher",
+	"GET Voucher/*/":               "GetVoucherAnonymously",
+	"GET Order/*/":                 "GetVoucherOrderAnonymously",
+	"POST ApproverEmail/":          "ResendApproverEmail",
+	"PUT ApproverEmail/":           "PutApproverEmail",
+	"GET VulnerabilityAssessment/": "VulnerabilityAssessmentRescan",
+}
+
+// anonymous lists the operations which need no bearer token
+var anonymous = map[string]bool{
+	"GetVoucherAnonymously":      true,
+	"GetVoucherOrderAnonymously": true,
+}
+
+// request bundles what the handlers need to know about a request
+type request struct {
+	*http.Request
+	param string
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, KvStorePath) {
+		if s.fail(w, "KvStore") {
+			return
+		}
+		if r.Method != "POST" || r.Header.Get("x-api-key") != s.KvStoreAuthorizationKey {
+			writeJSON(w, http.StatusForbidden, map[string]string{"message": "Forbidden"})
+			return
+		}
+		s.kvStore(w, r, strings.TrimPrefix(r.URL.Path, KvStorePath))
+		return
+	}
+	if r.URL.Path == CRLPath {
+		crl, err := s.CA.CRL()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pkix-crl")
+		w.Write(crl)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, DownloadPath) {
+		s.download(w, r, strings.TrimPrefix(r.URL.Path, DownloadPath))
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, APIPath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	operation, param := route(r.Method, strings.TrimPrefix(r.URL.Path, APIPath))
+	handler, ok := s.handlers[operation]
+	if !ok {
+		writeError(w, http.StatusNotFound, 0, "", "Unknown endpoint")
+		return
+	}
+	f := s.failure(operation)
+	if f != nil && !f.Processed {
+		s.writeFailure(w, f)
+		return
+	}
+	if s.Token != "" && !anonymous[operation] && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, 0, "", "Invalid OAuth2 bearer token")
+		return
+	}
+
+	if f != nil {
+		s.mu.Lock()
+		handler(httptest.NewRecorder(), &request{Request: r, param: param})
+		s.mu.Unlock()
+		s.writeFailure(w, f)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler(w, &request{Request: r, param: param})
+}
+
+// fail counts the call and writes a scripted failure, if any
+func (s *Server) fail(w http.ResponseWriter, operation string) bool {
+	f := s.failure(operation)
+	if f == nil {
+		return false
+	}
+	s.writeFailure(w, f)
+	return true
+}
+
+// failure counts the call and returns the next scripted failure, if any
+func (s *Server) failure(operation string) *Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[operation]++
+	if queue := s.failures[operation]; len(queue) > 0 {
+		current := queue[0]
+		if queue[0].Times--; queue[0].Times <= 0 {
+			s.failures[operation] = queue[1:]
+		}
+		return &current
+	}
+	return nil
+}
+
+// writeFailure writes a scripted failure
+func (s *Server) writeFailure(w http.ResponseWriter, f *Failure) {
+	if f.Delay > 0 {
+		time.Sleep(f.Delay)
+	}
+	if f.Drop {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter/time.Second)))
+	}
+	message := f.Message
+	if message == "" {
+		message = http.StatusText(f.StatusCode)
+	}
+	writeError(w, f.StatusCode, f.ErrorId, "", message)
+}
+
+// fieldError is a single scheme validation error
+type fieldError struct {
+	Key string `json:"key"`
+	Msg string `json:"msg"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, errorID int, field, message string, errs ...fieldError) {
+	writeJSON(w, status, struct {
+		Success    bool   `json:"success"`
+		Message    string `json:",omitempty"`
+		ErrorId    int    `json:",omitempty"`
+		ErrorField string `json:",omitempty"`
+		Errors     []fieldError
+	}{false, message, errorID, field, errs})
+}
+
+// invalid reports scheme validation errors (HTTP 417)
+func invalid(w http.ResponseWriter, errs ...fieldError) {
+	writeError(w, http.StatusExpectationFailed, -2011, errs[0].Key, "Invalid request data", errs...)
+}
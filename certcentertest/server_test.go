@@ -0,0 +1,202 @@
+package certcentertest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+)
+
+func newOrder(t *testing.T, commonName string) *certcenter.OrderRequest {
+	t.Helper()
+	pemCSR, _, err := csr.Generate(&csr.Request{CommonName: commonName}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 12,
+		},
+	}
+}
+
+func TestToken(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client(certcenter.WithBearer("wrong")).Profile(context.Background())
+	if !errors.Is(err, certcenter.ErrUnauthorized) {
+		t.Fatalf("got %v, want ErrUnauthorized", err)
+	}
+	if _, err := srv.Client().Profile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	res, err := c.Order(ctx, newOrder(t, "www.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() certcenter.OrderInfo {
+		t.Helper()
+		order, err := c.GetOrder(ctx, &certcenter.GetOrderRequest{CertCenterOrderID: res.CertCenterOrderID, IncludeFulfillment: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order.OrderInfo
+	}
+	if order := get(); order.OrderStatus.MajorStatus != certcenter.StatusPending || order.CommonName != "www.example.com" {
+		t.Fatalf("got %+v, want a pending order for www.example.com", order.OrderStatus)
+	}
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	order := get()
+	if order.OrderStatus.MajorStatus != certcenter.StatusComplete || order.Fulfillment.Certificate == "" {
+		t.Fatalf("got %+v, want a fulfilled order", order.OrderStatus)
+	}
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err == nil {
+		t.Error("completing an order twice should fail")
+	}
+
+	if _, err := c.Revoke(ctx, &certcenter.RevokeRequest{CertCenterOrderID: res.CertCenterOrderID, RevokeReason: certcenter.RevokeKeyCompromise}); err != nil {
+		t.Fatal(err)
+	}
+	if order := get(); order.OrderStatus.MajorStatus != certcenter.StatusRevoked {
+		t.Errorf("got %s, want %s", order.OrderStatus.MajorStatus, certcenter.StatusRevoked)
+	}
+
+	_, err = c.GetOrder(ctx, &certcenter.GetOrderRequest{CertCenterOrderID: 1})
+	if !errors.Is(err, certcenter.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusServiceUnavailable, ErrorId: -1, Times: 2})
+	for i := 0; i < 2; i++ {
+		var apiErr *certcenter.APIError
+		if _, err := c.Limit(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: got %v, want a 503 APIError", i+1, err)
+		}
+	}
+	if _, err := c.Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Limit"); n != 3 {
+		t.Errorf("got %d calls, want 3", n)
+	}
+
+	// a processed failure hides a placed order
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusBadGateway, Processed: true})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com")); err == nil {
+		t.Fatal("expected an error")
+	}
+	orders, err := c.GetOrders(ctx, &certcenter.GetOrdersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.OrderInfos) != 1 {
+		t.Errorf("got %d orders, want 1", len(orders.OrderInfos))
+	}
+
+	// net/http repeats idempotent requests on reused connections once
+	srv.FailNext("Limit", certcentertest.Failure{Drop: true, Times: 3})
+	if _, err := c.Limit(ctx); err == nil {
+		t.Error("expected an error for a dropped connection")
+	}
+}
+
+func TestVoucherAnonymously(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	created, err := c.CreateVoucher(ctx, &certcenter.CreateVoucherRequest{
+		OrderParameters: certcenter.OrderParameters{ProductCode: "Comodo.PositiveSSL", ValidityPeriod: 12},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := &certcenter.GetVoucherRequest{VoucherCode: created.VoucherCode}
+
+	// anonymous endpoints must not depend on the bearer token
+	anonymous := srv.Client(certcenter.WithBearer("wrong"))
+	vouchers, err := anonymous.GetVoucherAnonymously(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vouchers.Vouchers) != 1 || vouchers.Vouchers[0].Redeemed {
+		t.Fatalf("got %+v, want the unredeemed voucher", vouchers.Vouchers)
+	}
+	if _, err := anonymous.GetVoucherOrderAnonymously(ctx, code); !errors.Is(err, certcenter.ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound before redemption", err)
+	}
+
+	order := newOrder(t, "www.example.com")
+	redeemed, err := c.RedeemVoucher(ctx, &certcenter.RedeemVoucherRequest{
+		VoucherCode:     created.VoucherCode,
+		OrderParameters: order.OrderParameters,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vouchers, err = anonymous.GetVoucherOrderAnonymously(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vouchers.Vouchers[0]
+	if !v.Redeemed || v.RedeemInfo.CertCenterOrderID != redeemed.CertCenterOrderID || v.OrderParameters.CSR != order.OrderParameters.CSR {
+		t.Errorf("got %+v, want the redeemed order", v)
+	}
+}
+
+func TestSubjectAltNamesArePriced(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	order := newOrder(t, "www.example.com")
+	order.OrderParameters.ProductCode = "Comodo.PositiveSSLMDC"
+	order.OrderParameters.SubjectAltNames = []string{"example.com", "mail.example.com"}
+	if _, err := c.Order(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.CreateVoucher(ctx, &certcenter.CreateVoucherRequest{
+		OrderParameters: certcenter.OrderParameters{
+			ProductCode:     "Comodo.PositiveSSLMDC",
+			ValidityPeriod:  12,
+			SubjectAltNames: []string{"example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := c.Limit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 39 + 2*30 for the order, 39 + 30 for the voucher
+	if limit.LimitInfo.Used != 168 {
+		t.Errorf("used %v, want 168", limit.LimitInfo.Used)
+	}
+}
@@ -0,0 +1,150 @@
+package certcenter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBaseURL    = "https://api.certcenter.com/rest/v1/"
+	defaultKvStoreURL = "https://fauth-db.eu.certcenter.com/"
+)
+
+// Client talks to the CertCenter API on behalf of a single account.
+// Each Client carries its own credentials and settings, so several
+// clients may be used concurrently (eg. for different customers).
+type Client struct {
+	bearer     string
+	kvKey      string
+	baseURL    string
+	kvURL      string
+	userAgent  string
+	httpClient *http.Client
+
+	// used to build httpClient if none has been provided
+	roundTripper http.RoundTripper
+	tlsConfig    *tls.Config
+
+	retryPolicy RetryPolicy
+	limiter     limiter
+
+	// validate requests locally before sending them (see WithValidation)
+	validate bool
+	// product details for validation, loaded on first use
+	catalog     *Catalog
+	catalogOnce sync.Once
+
+	// guards spending, nil unless configured (see WithBudget)
+	budget *BudgetGuard
+
+	// legacy clients read Bearer and KvStoreAuthorizationKey
+	// instead of their own credentials
+	legacy bool
+}
+
+// Option configures a Client created by NewClient
+type Option func(*Client)
+
+// WithBearer sets the OAuth2 bearer token used to authenticate requests
+func WithBearer(token string) Option {
+	return func(c *Client) {
+		c.bearer = token
+	}
+}
+
+// WithKvStoreAuthorizationKey sets the "AlwaysOnSSL KV-Storage
+// Authorization-Key" used by KvStore
+func WithKvStoreAuthorizationKey(key string) Option {
+	return func(c *Client) {
+		c.kvKey = key
+	}
+}
+
+// WithBaseURL overrides the API base URL (https://api.certcenter.com/rest/v1/),
+// eg. to use a staging environment or a local fake (see certcentertest)
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithKvStoreURL overrides the URL of the kv-storage used by KvStore
+// (https://fauth-db.eu.certcenter.com/), eg. to use a regional endpoint
+func WithKvStoreURL(kvURL string) Option {
+	return func(c *Client) {
+		c.kvURL = kvURL
+	}
+}
+
+// WithHTTPClient lets you provide your own *http.Client. It takes
+// precedence over WithRoundTripper and the TLS options.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// NewClient returns a new Client configured by the given options
+//
+// 	client := certcenter.NewClient(
+// 		certcenter.WithBearer("aValidToken.oauth2.certcenter.com"),
+// 	)
+// 	res, err := client.Profile(context.Background())
+//
+func NewClient(options ...Option) *Client {
+	c := &Client{
+		baseURL: defaultBaseURL,
+		kvURL:   defaultKvStoreURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Transport: c.transport()}
+	}
+	c.limiter.init()
+	return c
+}
+
+// newDefaultClient returns the client behind the package-level functions
+func newDefaultClient() *Client {
+	c := NewClient()
+	c.legacy = true
+	return c
+}
+
+// bearerToken returns the OAuth2 token to authenticate with
+func (c *Client) bearerToken() string {
+	if c.legacy {
+		return Bearer
+	}
+	return c.bearer
+}
+
+// kvStoreAuthorizationKey returns the key to authenticate with at the kv-storage
+func (c *Client) kvStoreAuthorizationKey() string {
+	if c.legacy {
+		return KvStoreAuthorizationKey
+	}
+	return c.kvKey
+}
+
+// joinURL appends path to base, no matter whether base
+// ends with a slash or not
+func joinURL(base, path string) string {
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// newRequest returns an apiRequest bound to c
+func (c *Client) newRequest() *apiRequest {
+	return &apiRequest{client: c}
+}
@@ -0,0 +1,140 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestClientsKeepTheirCredentials(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	valid := srv.Client()
+	invalid := srv.Client(certcenter.WithBearer("someone else's token"))
+	ctx := context.Background()
+
+	// both clients are used at the same time, neither picks up
+	// the token of the other
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := valid.Profile(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := invalid.Profile(ctx); !errors.Is(err, certcenter.ErrUnauthorized) {
+				t.Errorf("got %v, want ErrUnauthorized", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestKvStore(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	request := &certcenter.KeyValueStoreRequest{Key: "www.example.com", Value: "201907151234abcd"}
+
+	if _, err := srv.Client().KvStore(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := srv.KvValue("www.example.com"); !ok || v != request.Value {
+		t.Errorf("got %q, want %q stored", v, request.Value)
+	}
+
+	_, err := srv.Client(certcenter.WithKvStoreAuthorizationKey("wrong")).KvStore(ctx, request)
+	var apiErr *certcenter.APIError
+	if !errors.Is(err, certcenter.ErrUnauthorized) || !errors.As(err, &apiErr) || apiErr.Message != "Forbidden" {
+		t.Errorf("got %v, want the kv-storage's 403", err)
+	}
+
+	// the bearer token is no substitute for the kv-storage key
+	c := certcenter.NewClient(
+		certcenter.WithBaseURL(srv.URL+certcentertest.APIPath),
+		certcenter.WithKvStoreURL(srv.URL+certcentertest.KvStorePath),
+		certcenter.WithBearer(srv.Token),
+	)
+	if _, err := c.KvStore(ctx, request); err == nil {
+		t.Error("expected an error without a kv-storage key")
+	}
+	if n := srv.Calls("KvStore"); n != 2 {
+		t.Errorf("kv-storage requested %d times, want 2", n)
+	}
+}
+
+// recordingTransport answers every request itself and
+// records the URLs requested
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, r.URL.String())
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(`{"success":true}`)),
+		ContentLength: int64(len(`{"success":true}`)),
+		Request:       r,
+	}, nil
+}
+
+func TestBaseURLs(t *testing.T) {
+	ctx := context.Background()
+	kv := &certcenter.KeyValueStoreRequest{Key: "www.example.com", Value: "hash"}
+
+	for _, tc := range []struct {
+		options      []certcenter.Option
+		api, kvStore string
+	}{
+		{
+			nil,
+			"https://api.certcenter.com/rest/v1/Limit",
+			"https://fauth-db.eu.certcenter.com/www.example.com",
+		},
+		{
+			[]certcenter.Option{
+				certcenter.WithBaseURL("https://sandbox.example.com/rest/v1"),
+				certcenter.WithKvStoreURL("https://kv.example.com/fauth"),
+			},
+			"https://sandbox.example.com/rest/v1/Limit",
+			"https://kv.example.com/fauth/www.example.com",
+		},
+		{
+			[]certcenter.Option{
+				certcenter.WithBaseURL("http://127.0.0.1:8080/rest/v1/"),
+				certcenter.WithKvStoreURL("http://127.0.0.1:8081/"),
+			},
+			"http://127.0.0.1:8080/rest/v1/Limit",
+			"http://127.0.0.1:8081/www.example.com",
+		},
+	} {
+		rt := new(recordingTransport)
+		c := certcenter.NewClient(append([]certcenter.Option{
+			certcenter.WithBearer("token"),
+			certcenter.WithKvStoreAuthorizationKey("key"),
+			certcenter.WithRoundTripper(rt),
+		}, tc.options...)...)
+		if _, err := c.Limit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.KvStore(ctx, kv); err != nil {
+			t.Fatal(err)
+		}
+		if len(rt.urls) != 2 || rt.urls[0] != tc.api || rt.urls[1] != tc.kvStore {
+			t.Errorf("got %q, want %q and %q", rt.urls, tc.api, tc.kvStore)
+		}
+	}
+}
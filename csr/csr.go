@@ -0,0 +1,221 @@
+// Package csr generates private keys and PKCS#10 certificate signing
+// requests, so no openssl is needed to order a certificate.
+//
+//	req := &csr.Request{CommonName: "www.example.com", SubjectAltNames: []string{"example.com"}}
+//	req.SetOrganization(orderRequest.OrganizationInfo)
+//	pem, key, err := csr.Generate(req, csr.ECDSAP256)
+//	if err != nil {
+//		..
+//	}
+//	orderRequest.OrderParameters.CSR = pem
+//
+// Keep the key (see EncodeKey) to install the certificate later.
+package csr
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	certcenter "certcenter.com/go"
+)
+
+// KeyType selects the algorithm and size of a generated key
+type KeyType string
+
+const (
+	RSA2048   KeyType = "RSA-2048"
+	RSA3072   KeyType = "RSA-3072"
+	RSA4096   KeyType = "RSA-4096"
+	ECDSAP256 KeyType = "ECDSA-P256"
+	ECDSAP384 KeyType = "ECDSA-P384"
+)
+
+// GenerateKey returns a new private key of type t
+func GenerateKey(t KeyType) (crypto.Signer, error) {
+	switch t {
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	}
+	return nil, fmt.Errorf("csr: unknown key type %q", t)
+}
+
+// EncodeKey returns key as PEM-encoded PKCS#8 ("PRIVATE KEY")
+func EncodeKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParseKey reads a PEM-encoded PKCS#8, PKCS#1 (RSA) or SEC 1 (EC)
+// private key
+func ParseKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("csr: no PEM-encoded private key found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("csr: unsupported private key %T", key)
+	}
+	return signer, nil
+}
+
+// Request describes the subject of a CSR
+type Request struct {
+	CommonName string
+	// SubjectAltNames may hold host names, IP addresses and email
+	// addresses. The CommonName is added as a SAN if it's a host name.
+	SubjectAltNames []string
+
+	Organization       string
+	OrganizationalUnit string
+	StreetAddress      string
+	PostalCode         string
+	Locality           string
+	Province           string
+	Country            string // ISO 3166-1 alpha-2
+	Email              string
+
+	// SignatureAlgorithm defaults to the key's algorithm with a hash
+	// matching its size: SHA-256 for RSA and P-256 keys, SHA-384 for
+	// P-384 keys
+	SignatureAlgorithm x509.SignatureAlgorithm
+}
+
+// SetOrganization copies the organization's name and address into the
+// subject. A nil info is ignored.
+func (r *Request) SetOrganization(info *certcenter.OrganizationInfo) {
+	if info == nil {
+		return
+	}
+	r.Organization = info.OrganizationName
+	if a := info.OrganizationAddress; a != nil {
+		r.StreetAddress = a.AddressLine1
+		r.PostalCode = a.PostalCode
+		r.Locality = a.City
+		r.Province = a.Region
+		r.Country = a.Country
+	}
+}
+
+// template builds the x509 template of r
+func (r *Request) template() (*x509.CertificateRequest, error) {
+	if r.CommonName == "" && len(r.SubjectAltNames) == 0 {
+		return nil, errors.New("csr: CommonName or SubjectAltNames required")
+	}
+	if r.Country != "" && len(r.Country) != 2 {
+		return nil, fmt.Errorf("csr: Country %q is not an ISO 3166-1 alpha-2 code", r.Country)
+	}
+
+	tmpl := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         r.CommonName,
+			Organization:       nonEmpty(r.Organization),
+			OrganizationalUnit: nonEmpty(r.OrganizationalUnit),
+			StreetAddress:      nonEmpty(r.StreetAddress),
+			PostalCode:         nonEmpty(r.PostalCode),
+			Locality:           nonEmpty(r.Locality),
+			Province:           nonEmpty(r.Province),
+			Country:            nonEmpty(strings.ToUpper(r.Country)),
+		},
+		SignatureAlgorithm: r.SignatureAlgorithm,
+	}
+	if r.Email != "" {
+		tmpl.EmailAddresses = append(tmpl.EmailAddresses, r.Email)
+	}
+
+	seen := make(map[string]bool)
+	names := r.SubjectAltNames
+	if isHostName(r.CommonName) {
+		names = append([]string{r.CommonName}, names...)
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		switch {
+		case net.ParseIP(name) != nil:
+			tmpl.IPAddresses = append(tmpl.IPAddresses, net.ParseIP(name))
+		case strings.Contains(name, "@"):
+			if name != r.Email {
+				tmpl.EmailAddresses = append(tmpl.EmailAddresses, name)
+			}
+		default:
+			tmpl.DNSNames = append(tmpl.DNSNames, name)
+		}
+	}
+	return tmpl, nil
+}
+
+func isHostName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "@ ") && net.ParseIP(name) == nil
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// Create returns a PEM-encoded CSR for r, signed with key. It can be
+// used as is for OrderParameters.CSR, ReissueOrderParameters.CSR,
+// DNSDataRequest.CSR and FileDataRequest.CSR.
+func Create(r *Request, key crypto.Signer) (string, error) {
+	tmpl, err := r.template()
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), nil
+}
+
+// Generate creates a new key of type t and a CSR for r signed with it
+func Generate(r *Request, t KeyType) (csr string, key crypto.Signer, err error) {
+	if key, err = GenerateKey(t); err != nil {
+		return "", nil, err
+	}
+	if csr, err = Create(r, key); err != nil {
+		return "", nil, err
+	}
+	return csr, key, nil
+}
@@ -0,0 +1,242 @@
+package csr_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+)
+
+// parse decodes a PEM-encoded CSR and checks its signature
+func parse(t *testing.T, pemCSR string) *x509.CertificateRequest {
+	t.Helper()
+	block, _ := pem.Decode([]byte(pemCSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatalf("not a PEM-encoded CSR: %q", pemCSR)
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestGenerate(t *testing.T) {
+	for _, tc := range []struct {
+		keyType   csr.KeyType
+		algorithm string
+		keyLength int
+		signature x509.SignatureAlgorithm
+	}{
+		{csr.RSA2048, "RSA", 2048, x509.SHA256WithRSA},
+		{csr.RSA3072, "RSA", 3072, x509.SHA256WithRSA},
+		{csr.RSA4096, "RSA", 4096, x509.SHA256WithRSA},
+		{csr.ECDSAP256, "ECDSA", 256, x509.ECDSAWithSHA256},
+		{csr.ECDSAP384, "ECDSA", 384, x509.ECDSAWithSHA384},
+	} {
+		if testing.Short() && tc.keyLength > 2048 && tc.algorithm == "RSA" {
+			continue
+		}
+		pemCSR, key, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, tc.keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details, err := certcenter.ParseCSR(pemCSR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.KeyEncryptionAlgorithm != tc.algorithm || details.KeyLength != tc.keyLength {
+			t.Errorf("%s: got a %d bit %s key", tc.keyType, details.KeyLength, details.KeyEncryptionAlgorithm)
+		}
+		if w := details.Weaknesses(); len(w) > 0 {
+			t.Errorf("%s: %v", tc.keyType, w)
+		}
+		request := parse(t, pemCSR)
+		if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(request.PublicKey) {
+			t.Errorf("%s: the CSR isn't for the returned key", tc.keyType)
+		}
+		if request.SignatureAlgorithm != tc.signature {
+			t.Errorf("%s: signed with %v, want %v", tc.keyType, request.SignatureAlgorithm, tc.signature)
+		}
+	}
+
+	if _, _, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, "DSA-1024"); err == nil {
+		t.Error("expected an unknown key type to fail")
+	}
+}
+
+func TestRequestNames(t *testing.T) {
+	pemCSR, _, err := csr.Generate(&csr.Request{
+		CommonName:      "www.example.com",
+		SubjectAltNames: []string{"example.com", "WWW.example.com", " ", "192.0.2.1", "admin@example.com"},
+		Email:           "admin@example.com",
+	}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := parse(t, pemCSR)
+	if req.Subject.CommonName != "www.example.com" {
+		t.Errorf("got CommonName %q", req.Subject.CommonName)
+	}
+	if len(req.DNSNames) != 2 || req.DNSNames[0] != "www.example.com" || req.DNSNames[1] != "example.com" {
+		t.Errorf("got DNSNames %v", req.DNSNames)
+	}
+	if len(req.IPAddresses) != 1 || req.IPAddresses[0].String() != "192.0.2.1" {
+		t.Errorf("got IPAddresses %v", req.IPAddresses)
+	}
+	if len(req.EmailAddresses) != 1 || req.EmailAddresses[0] != "admin@example.com" {
+		t.Errorf("got EmailAddresses %v", req.EmailAddresses)
+	}
+
+	// a CommonName which isn't a host name is not added as a SAN
+	pemCSR, _, err = csr.Generate(&csr.Request{CommonName: "Example Inc.", SubjectAltNames: []string{"example.com"}}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := parse(t, pemCSR); len(req.DNSNames) != 1 || req.DNSNames[0] != "example.com" {
+		t.Errorf("got DNSNames %v", req.DNSNames)
+	}
+}
+
+func TestSetOrganization(t *testing.T) {
+	r := &csr.Request{CommonName: "www.example.com"}
+	r.SetOrganization(nil)
+	r.SetOrganization(&certcenter.OrganizationInfo{
+		OrganizationName: "Example Inc.",
+		OrganizationAddress: &certcenter.OrganizationAddress{
+			AddressLine1: "1 Example Street",
+			PostalCode:   "12345",
+			City:         "Example City",
+			Region:       "Example Region",
+			Country:      "de",
+		},
+	})
+	pemCSR, _, err := csr.Generate(r, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := parse(t, pemCSR).Subject
+	if len(s.Organization) != 1 || s.Organization[0] != "Example Inc." ||
+		len(s.StreetAddress) != 1 || s.StreetAddress[0] != "1 Example Street" ||
+		len(s.PostalCode) != 1 || s.PostalCode[0] != "12345" ||
+		len(s.Locality) != 1 || s.Locality[0] != "Example City" ||
+		len(s.Province) != 1 || s.Province[0] != "Example Region" ||
+		len(s.Country) != 1 || s.Country[0] != "DE" {
+		t.Errorf("got subject %+v", s)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	key, err := csr.GenerateKey(csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*csr.Request{
+		{},
+		{CommonName: "www.example.com", Country: "Germany"},
+	} {
+		if _, err := csr.Create(r, key); err == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	key, err := csr.GenerateKey(csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := csr.EncodeKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := pem.Decode([]byte(encoded)); block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("got %q, want a PKCS#8 key", encoded)
+	}
+	parsed, err := csr.ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("the parsed key differs")
+	}
+
+	// keys written by openssl
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		{Type: "EC PRIVATE KEY", Bytes: sec1},
+	} {
+		if _, err := csr.ParseKey(string(pem.EncodeToMemory(block))); err != nil {
+			t.Errorf("%s: %v", block.Type, err)
+		}
+	}
+
+	for _, data := range []string{
+		"",
+		"not a key",
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}})),
+	} {
+		if _, err := csr.ParseKey(data); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
+
+func TestOrderWithGeneratedCSR(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+
+	pemCSR, key, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, csr.RSA2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := srv.Client().Order(context.Background(), &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 12,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := srv.Order(res.CertCenterOrderID)
+	block, _ := pem.Decode([]byte(order.Fulfillment.Certificate))
+	if block == nil {
+		t.Fatal("no certificate issued")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.(*rsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Error("the certificate isn't for the generated key")
+	}
+}
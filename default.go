@@ -0,0 +1,229 @@
+package certcenter
+
+import (
+	"context"
+)
+
+// std is the client behind the package-level functions. It resolves its
+// credentials from Bearer and KvStoreAuthorizationKey on every call, so
+// existing code that assigns those variables keeps working. Use a Client
+// if you need cancellation or deadlines (see context.Context).
+var std = newDefaultClient()
+
+// Profile fetches basic informations about your profile
+//
+func Profile() (*ProfileResult, error) {
+	return std.Profile(context.Background())
+}
+
+// Limit inquires information about your current limit and used amount
+//
+func Limit() (*LimitResult, error) {
+	return std.Limit(context.Background())
+}
+
+// Products allows you to fetch a list of valid ProductCodes
+//
+func Products() (*ProductsResult, error) {
+	return std.Products(context.Background())
+}
+
+// ProductDetails inquires detailed information on a particular ProductCode
+//
+func ProductDetails(ProductCode string) (*ProductDetailsResult, error) {
+	return std.ProductDetails(context.Background(), ProductCode)
+}
+
+// Quote allows you to generate an individual real-time quotation
+//
+func Quote(request *QuoteRequest) (*QuoteResult, error) {
+	return std.Quote(context.Background(), request)
+}
+
+// ValidateCSR allows you to parse and validate a PEM-encoded PKCS#10
+//
+func ValidateCSR(request *ValidateCSRRequest) (*ValidateCSRResult, error) {
+	return std.ValidateCSR(context.Background(), request)
+}
+
+// UserAgreement fetches the latest subscriber agreement from the CA
+//
+func UserAgreement(ProductCode string) (*UserAgreementResult, error) {
+	return std.UserAgreement(context.Background(), ProductCode)
+}
+
+// ApproverList will fetch a list of valid email addresses
+// for a particular CommonName and ProductCode
+func ApproverList(request *ApproverListRequest) (*ApproverListResult, error) {
+	return std.ApproverList(context.Background(), request)
+}
+
+// Order allows you to submit orders for regular certificates
+// as well as S/MIME and AlwaysOnSSL certificates
+//
+func Order(request *OrderRequest) (*OrderResult, error) {
+	return std.Order(context.Background(), request)
+}
+
+// PutApproverEmail allows you to reset the email address of the approver
+//
+func PutApproverEmail(request *PutApproverEmailRequest) (*PutApproverEmailResult, error) {
+	return std.PutApproverEmail(context.Background(), request)
+}
+
+// ResendApproverEmail allows you to resend the approver email to the approvers address
+//
+func ResendApproverEmail(request *ResendApproverEmailRequest) (*ResendApproverEmailResult, error) {
+	return std.ResendApproverEmail(context.Background(), request)
+}
+
+// GetOrders gives you the capability to query and filter your orders
+//
+func GetOrders(request *GetOrdersRequest) (*GetOrdersResult, error) {
+	return std.GetOrders(context.Background(), request)
+}
+
+// GetModifiedOrders fetches modified orders. You can provide
+// a timespan to specify which changes your're interested in
+//
+func GetModifiedOrders(request *GetModifiedOrdersRequest) (*GetModifiedOrdersResult, error) {
+	return std.GetModifiedOrders(context.Background(), request)
+}
+
+// GetOrder gives you the capability to query a particular order
+//
+func GetOrder(request *GetOrderRequest) (*GetOrderResult, error) {
+	return std.GetOrder(context.Background(), request)
+}
+
+// DeleteOrder gives you the capability to cancel a order
+//
+func DeleteOrder(request *DeleteOrderRequest) (*DeleteOrderResult, error) {
+	return std.DeleteOrder(context.Background(), request)
+}
+
+// Reissue allows you to replace an existent certificate in case
+// of a key loss or algorithm/key-size upgrade
+//
+func Reissue(request *ReissueRequest) (*ReissueResult, error) {
+	return std.Reissue(context.Background(), request)
+}
+
+// Revoke allows you to mark a certificate as invalid.
+//
+func Revoke(request *RevokeRequest) (*RevokeResult, error) {
+	return std.Revoke(context.Background(), request)
+}
+
+// BaseDomain allows you to fetch a registered base domain for a FQDN
+//
+func BaseDomain(request *BaseDomainRequest) (*BaseDomainResult, error) {
+	return std.BaseDomain(context.Background(), request)
+}
+
+// ValidateName checks a CommonName against the DigiCert EE blacklist
+// (AlwaysOnSSL/DigiCert EE only)
+//
+func ValidateName(request *ValidateNameRequest) (*ValidateNameResult, error) {
+	return std.ValidateName(context.Background(), request)
+}
+
+// DNSData retrieve appropriate data for DNS based validation
+// (AlwaysOnSSL/DigiCert EE only)
+//
+func DNSData(request *DNSDataRequest) (*DNSDataResult, error) {
+	return std.DNSData(context.Background(), request)
+}
+
+// FileData retrieve appropriate data for FILE based validation
+// (AlwaysOnSSL/DigiCert EE only)
+//
+func FileData(request *FileDataRequest) (*FileDataResult, error) {
+	return std.FileData(context.Background(), request)
+}
+
+// VulnerabilityAssessment allows you to configure the
+// Vulnerability Assessment (DigiCert certificates, only!)
+//
+func VulnerabilityAssessment(request *VulnerabilityAssessmentRequest) (*VulnerabilityAssessmentResult, error) {
+	return std.VulnerabilityAssessment(context.Background(), request)
+}
+
+// VulnerabilityAssessmentRescan let you initiate a re-scan for a certain order
+//
+func VulnerabilityAssessmentRescan(request *VulnerabilityAssessmentRescanRequest) (*VulnerabilityAssessmentRescanResult, error) {
+	return std.VulnerabilityAssessmentRescan(context.Background(), request)
+}
+
+// CreateUser creates a new user and assign the desired rights
+//
+func CreateUser(request *CreateUserRequest) (*CreateUserResult, error) {
+	return std.CreateUser(context.Background(), request)
+}
+
+// UpdateUser updates an user
+//
+func UpdateUser(request *UpdateUserRequest) (*UpdateUserResult, error) {
+	return std.UpdateUser(context.Background(), request)
+}
+
+// GetUser inquires information about a certain user or even all your
+// users (if you keep UserData.UsernameOrUserId blank)
+//
+func GetUser(request *GetUserRequest) (*GetUserResult, error) {
+	return std.GetUser(context.Background(), request)
+}
+
+// DeleteUser allows you to delete an user
+//
+func DeleteUser(request *DeleteUserRequest) (*DeleteUserResult, error) {
+	return std.DeleteUser(context.Background(), request)
+}
+
+// KvStore allows you to use mod_fauth with CertCenter's free kv-storage
+//
+func KvStore(request *KeyValueStoreRequest) (*KeyValueStoreResult, error) {
+	return std.KvStore(context.Background(), request)
+}
+
+// CreateVoucher creates a coupon code which can later be redeemded.
+//
+func CreateVoucher(request *CreateVoucherRequest) (*CreateVoucherResult, error) {
+	return std.CreateVoucher(context.Background(), request)
+}
+
+// RedeemVoucher let you redeem a previously generated voucher code
+//
+func RedeemVoucher(request *RedeemVoucherRequest) (*RedeemVoucherResult, error) {
+	return std.RedeemVoucher(context.Background(), request)
+}
+
+// GetVouchers inquires information about all your voucher codes.
+//
+func GetVouchers() (*GetVouchersResult, error) {
+	return std.GetVouchers(context.Background())
+}
+
+// GetVoucher inquires information about a particular voucher.
+//
+func GetVoucher(request *GetVoucherRequest) (*GetVouchersResult, error) {
+	return std.GetVoucher(context.Background(), request)
+}
+
+// GetVoucherAnonymously inquires information about a particular voucher.
+//
+func GetVoucherAnonymously(request *GetVoucherRequest) (*GetVouchersResult, error) {
+	return std.GetVoucherAnonymously(context.Background(), request)
+}
+
+// GetVoucherOrderAnonymously inquires information about a order initiated by func RedeemVoucher(..).
+//
+func GetVoucherOrderAnonymously(request *GetVoucherRequest) (*GetVouchersResult, error) {
+	return std.GetVoucherOrderAnonymously(context.Background(), request)
+}
+
+// DeleteVoucher allows you to invalidate a particular voucher code.
+//
+func DeleteVoucher(request *DeleteVoucherRequest) (*DeleteVoucherResult, error) {
+	return std.DeleteVoucher(context.Background(), request)
+}
@@ -0,0 +1,62 @@
+package certcenter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// The package-level functions can't be pointed at certcentertest, which
+// imports this package, so a plain httptest server stands in
+func TestDefaultClientReadsGlobals(t *testing.T) {
+	var authorization, apiKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		apiKey = r.Header.Get("x-api-key")
+		w.Write([]byte(`{"success":true,"message":"ok"}`))
+	}))
+	defer ts.Close()
+
+	bearer, kvKey, baseURL, kvURL := Bearer, KvStoreAuthorizationKey, std.baseURL, std.kvURL
+	defer func() {
+		Bearer, KvStoreAuthorizationKey, std.baseURL, std.kvURL = bearer, kvKey, baseURL, kvURL
+	}()
+	std.baseURL, std.kvURL = ts.URL, ts.URL
+
+	// credentials are read on every call, not when std is created
+	for _, token := range []string{"first", "second"} {
+		Bearer = token
+		if _, err := Profile(); err != nil {
+			t.Fatal(err)
+		}
+		if authorization != "Bearer "+token {
+			t.Errorf("got Authorization %q, want the current Bearer %q", authorization, token)
+		}
+	}
+
+	KvStoreAuthorizationKey = "kv-key"
+	if _, err := KvStore(&KeyValueStoreRequest{Key: "www.example.com", Value: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "kv-key" {
+		t.Errorf("got x-api-key %q, want KvStoreAuthorizationKey", apiKey)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithBaseURL(ts.URL), WithBearer("token"), WithUserAgent("reseller/1.0"))
+	if _, err := c.Profile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if userAgent != "reseller/1.0" {
+		t.Errorf("got User-Agent %q, want reseller/1.0", userAgent)
+	}
+}
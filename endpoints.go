@@ -0,0 +1,151 @@
+package certcenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// endpoint describes how an API operation maps onto HTTP
+type endpoint struct {
+	method string
+	// path relative to the base URL. Placeholders like {CertCenterOrderID}
+	// are filled from request fields tagged `path:"CertCenterOrderID"`
+	path string
+	// params is a combination of CC_PARAM_TYPE_QS, CC_PARAM_TYPE_PATH
+	// and CC_PARAM_TYPE_BODY
+	params int
+	// anonymous endpoints are called without Authorization header
+	anonymous bool
+}
+
+// endpoints maps the names of the operations in certcenter.go to their
+// HTTP representation. Adding an API endpoint only requires a new entry
+// here (and path tags on the request type, if necessary).
+var endpoints = map[string]endpoint{
+	"Profile":                       {method: "GET", path: "Profile"},
+	"Limit":                         {method: "GET", path: "Limit"},
+	"Products":                      {method: "GET", path: "Products"},
+	"ProductDetails":                {method: "GET", path: "ProductDetails", params: CC_PARAM_TYPE_QS},
+	"Quote":                         {method: "GET", path: "Quote", params: CC_PARAM_TYPE_QS},
+	"ValidateCSR":                   {method: "POST", path: "ValidateCSR", params: CC_PARAM_TYPE_BODY},
+	"UserAgreement":                 {method: "GET", path: "UserAgreement", params: CC_PARAM_TYPE_QS},
+	"ApproverList":                  {method: "GET", path: "ApproverList", params: CC_PARAM_TYPE_QS},
+	"Order":                         {method: "POST", path: "Order", params: CC_PARAM_TYPE_BODY},
+	"PutApproverEmail":              {method: "PUT", path: "ApproverEmail/{CertCenterOrderID}", params: CC_PARAM_TYPE_QS | CC_PARAM_TYPE_PATH},
+	"ResendApproverEmail":           {method: "POST", path: "ApproverEmail/{CertCenterOrderID}", params: CC_PARAM_TYPE_PATH},
+	"GetOrders":                     {method: "GET", path: "Orders", params: CC_PARAM_TYPE_QS},
+	"GetModifiedOrders":             {method: "GET", path: "GetModifiedOrders", params: CC_PARAM_TYPE_QS},
+	"GetOrder":                      {method: "GET", path: "Order/{CertCenterOrderID}", params: CC_PARAM_TYPE_QS | CC_PARAM_TYPE_PATH},
+	"DeleteOrder":                   {method: "DELETE", path: "Order/{CertCenterOrderID}", params: CC_PARAM_TYPE_PATH},
+	"Reissue":                       {method: "POST", path: "Reissue", params: CC_PARAM_TYPE_BODY},
+	"Revoke":                        {method: "DELETE", path: "Revoke/{CertCenterOrderID}", params: CC_PARAM_TYPE_BODY | CC_PARAM_TYPE_PATH},
+	"BaseDomain":                    {method: "GET", path: "BaseDomain/{FQDN}", params: CC_PARAM_TYPE_PATH},
+	"ValidateName":                  {method: "POST", path: "ValidateName", params: CC_PARAM_TYPE_BODY},
+	"DNSData":                       {method: "POST", path: "DNSData", params: CC_PARAM_TYPE_BODY},
+	"FileData":                      {method: "POST", path: "FileData", params: CC_PARAM_TYPE_BODY},
+	"VulnerabilityAssessment":       {method: "POST", path: "VulnerabilityAssessment", params: CC_PARAM_TYPE_BODY},
+	"VulnerabilityAssessmentRescan": {method: "GET", path: "VulnerabilityAssessment/{CertCenterOrderID}", params: CC_PARAM_TYPE_PATH},
+	"CreateUser":                    {method: "POST", path: "User", params: CC_PARAM_TYPE_BODY},
+	"UpdateUser":                    {method: "POST", path: "User/{UsernameOrUserId}", params: CC_PARAM_TYPE_BODY | CC_PARAM_TYPE_PATH},
+	"GetUser":                       {method: "GET", path: "User/{UsernameOrUserId}", params: CC_PARAM_TYPE_PATH},
+	"DeleteUser":                    {method: "DELETE", path: "User/{UsernameOrUserId}", params: CC_PARAM_TYPE_PATH},
+	"CreateVoucher":                 {method: "POST", path: "Voucher", params: CC_PARAM_TYPE_BODY},
+	"RedeemVoucher":                 {method: "POST", path: "Redeem", params: CC_PARAM_TYPE_BODY},
+	"GetVouchers":                   {method: "GET", path: "Vouchers"},
+	"GetVoucher":                    {method: "GET", path: "Voucher/{VoucherCode}", params: CC_PARAM_TYPE_PATH},
+	"GetVoucherAnonymously":         {method: "GET", path: "Voucher/*/{VoucherCode}", params: CC_PARAM_TYPE_PATH, anonymous: true},
+	"GetVoucherOrderAnonymously":    {method: "GET", path: "Order/*/{VoucherCode}", params: CC_PARAM_TYPE_PATH, anonymous: true},
+	"DeleteVoucher":                 {method: "DELETE", path: "Voucher/{VoucherCode}", params: CC_PARAM_TYPE_PATH},
+}
+
+// pathParam is a request field bound to a path placeholder
+type pathParam struct {
+	value    string
+	queryKey string // name of the field in the query string
+	jsonKey  string // name of the field in the JSON body
+}
+
+// pathParams collects all fields tagged with `path:".."` (including
+// those of embedded structs) from the request v
+func pathParams(v interface{}) map[string]pathParam {
+	params := make(map[string]pathParam)
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return params
+		}
+		val = val.Elem()
+	}
+	if val.Kind() == reflect.Struct {
+		collectPathParams(params, val)
+	}
+	return params
+}
+
+func collectPathParams(params map[string]pathParam, val reflect.Value) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			collectPathParams(params, val.Field(i))
+			continue
+		}
+		name := sf.Tag.Get("path")
+		if name == "" || sf.PkgPath != "" {
+			continue
+		}
+		p := pathParam{
+			value:    fmt.Sprint(val.Field(i).Interface()),
+			queryKey: sf.Name,
+			jsonKey:  sf.Name,
+		}
+		if tag := strings.Split(sf.Tag.Get("url"), ",")[0]; tag != "" {
+			p.queryKey = tag
+		}
+		if tag := strings.Split(sf.Tag.Get("json"), ",")[0]; tag != "" {
+			p.jsonKey = tag
+		}
+		params[name] = p
+	}
+}
+
+// expandPath replaces the placeholders in path by the values of the
+// corresponding path params. Empty values at the end of the path are
+// dropped (eg. GET /User lists all users).
+func expandPath(path string, params map[string]pathParam) (string, error) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := segment[1 : len(segment)-1]
+		p, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("CertCenter API: Request lacks a field tagged `path:%q`", name)
+		}
+		segments[i] = url.PathEscape(p.value)
+	}
+	for len(segments) > 1 && segments[len(segments)-1] == "" {
+		segments = segments[:len(segments)-1]
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// encodeBody marshals the request to JSON, leaving out path params
+func encodeBody(v interface{}, params map[string]pathParam) ([]byte, error) {
+	d, err := json.Marshal(v)
+	if err != nil || len(params) == 0 {
+		return d, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(d, &fields); err != nil {
+		return d, nil
+	}
+	for _, p := range params {
+		delete(fields, p.jsonKey)
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,121 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestUserEndpoints(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	created, err := c.CreateUser(ctx, &certcenter.CreateUserRequest{UserData: certcenter.UserData{
+		Username: "jdoe",
+		FullName: "J. Doe",
+		Roles:    []string{"PROCUREMENT"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := c.GetUser(ctx, &certcenter.GetUserRequest{UserData: certcenter.UserData{UsernameOrUserId: "jdoe"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != created.Id {
+		t.Errorf("got user %d, want %d", user.Id, created.Id)
+	}
+	// without UsernameOrUserId, all users are listed
+	if _, err := c.GetUser(ctx, &certcenter.GetUserRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdateUser(ctx, &certcenter.UpdateUserRequest{UserData: certcenter.UserData{
+		UsernameOrUserId: "jdoe",
+		FullName:         "Jane Doe",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(created.Id, 10)
+	if _, err := c.DeleteUser(ctx, &certcenter.DeleteUserRequest{UsernameOrUserId: id}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetUser(ctx, &certcenter.GetUserRequest{UserData: certcenter.UserData{UsernameOrUserId: id}})
+	if !errors.Is(err, certcenter.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for the deleted user", err)
+	}
+	if n := srv.Calls("GetUser"); n != 3 {
+		t.Errorf("GetUser reached the server %d times, want 3", n)
+	}
+}
+
+func TestVulnerabilityAssessmentRescan(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	id := placeOrder(t, srv, "www.example.com")
+	if _, err := c.VulnerabilityAssessmentRescan(ctx, &certcenter.VulnerabilityAssessmentRescanRequest{CertCenterOrderID: id}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.VulnerabilityAssessmentRescan(ctx, &certcenter.VulnerabilityAssessmentRescanRequest{CertCenterOrderID: id + 1})
+	if !errors.Is(err, certcenter.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	type call struct {
+		method, path, body string
+	}
+	var calls []call
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		calls = append(calls, call{r.Method, r.URL.EscapedPath(), string(body)})
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+	c := certcenter.NewClient(certcenter.WithBaseURL(ts.URL), certcenter.WithBearer("token"))
+	ctx := context.Background()
+
+	if _, err := c.DeleteUser(ctx, &certcenter.DeleteUserRequest{UsernameOrUserId: "j doe/admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdateUser(ctx, &certcenter.UpdateUserRequest{UserData: certcenter.UserData{
+		UsernameOrUserId: "jdoe",
+		FullName:         "Jane Doe",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetVoucherAnonymously(ctx, &certcenter.GetVoucherRequest{VoucherCode: "ABC123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []call{
+		{"DELETE", "/User/j%20doe%2Fadmin", ""},
+		{"POST", "/User/jdoe", ""},
+		{"GET", "/Voucher/*/ABC123", ""},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("got %d calls, want %d", len(calls), len(want))
+	}
+	for i, w := range want {
+		if calls[i].method != w.method || calls[i].path != w.path {
+			t.Errorf("got %s %s, want %s %s", calls[i].method, calls[i].path, w.method, w.path)
+		}
+	}
+	// path params are not repeated in the body
+	if body := calls[1].body; strings.Contains(body, "UsernameOrUserId") || !strings.Contains(body, "Jane Doe") {
+		t.Errorf("got body %s", body)
+	}
+}
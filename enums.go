@@ -0,0 +1,210 @@
+package certcenter
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MajorStatus is the overall status of an order
+type MajorStatus string
+
+const (
+	StatusPending   MajorStatus = "PENDING"
+	StatusComplete  MajorStatus = "COMPLETE"
+	StatusCancelled MajorStatus = "CANCELLED"
+	StatusRevoked   MajorStatus = "REVOKED"
+)
+
+// IsValid reports whether s is a known MajorStatus
+func (s MajorStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusComplete, StatusCancelled, StatusRevoked:
+		return true
+	}
+	return false
+}
+
+// ProductType is the kind of certificate (see GetOrdersRequest)
+type ProductType string
+
+const (
+	ProductTypeSSL       ProductType = "SSL"
+	ProductTypeCodeSign  ProductType = "CODESIGN"
+	ProductTypeSMIME     ProductType = "SMIME"
+	ProductTypeTrustSeal ProductType = "TRUSTSEAL"
+)
+
+// IsValid reports whether t is a known ProductType
+func (t ProductType) IsValid() bool {
+	switch t {
+	case ProductTypeSSL, ProductTypeCodeSign, ProductTypeSMIME, ProductTypeTrustSeal:
+		return true
+	}
+	return false
+}
+
+// DVAuthMethod is the method of domain control validation
+type DVAuthMethod string
+
+const (
+	DVAuthDNS   DVAuthMethod = "DNS"
+	DVAuthEmail DVAuthMethod = "EMAIL"
+	DVAuthFile  DVAuthMethod = "FILE"
+)
+
+// IsValid reports whether m is a known DVAuthMethod
+func (m DVAuthMethod) IsValid() bool {
+	switch m {
+	case DVAuthDNS, DVAuthEmail, DVAuthFile:
+		return true
+	}
+	return false
+}
+
+// RevokeReason tells why a certificate is revoked (see RFC 5280, 5.3.1)
+type RevokeReason string
+
+const (
+	RevokeUnspecified          RevokeReason = "unspecified"
+	RevokeKeyCompromise        RevokeReason = "keyCompromise"
+	RevokeAffiliationChanged   RevokeReason = "affiliationChanged"
+	RevokeSuperseded           RevokeReason = "superseded"
+	RevokeCessationOfOperation RevokeReason = "cessationOfOperation"
+	RevokePrivilegeWithdrawn   RevokeReason = "privilegeWithdrawn"
+)
+
+// IsValid reports whether r is a known RevokeReason
+func (r RevokeReason) IsValid() bool {
+	switch r {
+	case RevokeUnspecified, RevokeKeyCompromise, RevokeAffiliationChanged,
+		RevokeSuperseded, RevokeCessationOfOperation, RevokePrivilegeWithdrawn:
+		return true
+	}
+	return false
+}
+
+// SignatureHashAlgorithm selects the signature algorithm of the issued
+// certificate and its chain
+type SignatureHashAlgorithm string
+
+const (
+	SignatureSHA256FullChain SignatureHashAlgorithm = "SHA256-FULL-CHAIN"
+	SignatureSHA256ECCFull   SignatureHashAlgorithm = "SHA256-ECC-FULL"
+	SignatureSHA256ECCHybrid SignatureHashAlgorithm = "SHA256-ECC-HYBRID"
+	SignatureSHA384FullChain SignatureHashAlgorithm = "SHA384-FULL-CHAIN"
+	SignatureSHA384ECCFull   SignatureHashAlgorithm = "SHA384-ECC-FULL"
+)
+
+// IsValid reports whether a is a known SignatureHashAlgorithm
+func (a SignatureHashAlgorithm) IsValid() bool {
+	switch a {
+	case SignatureSHA256FullChain, SignatureSHA256ECCFull, SignatureSHA256ECCHybrid,
+		SignatureSHA384FullChain, SignatureSHA384ECCFull:
+		return true
+	}
+	return false
+}
+
+// ServiceStatus switches the vulnerability assessment of an order on or off
+type ServiceStatus string
+
+const (
+	ServiceActive   ServiceStatus = "Active"
+	ServiceInactive ServiceStatus = "Inactive"
+)
+
+// IsValid reports whether s is a known ServiceStatus
+func (s ServiceStatus) IsValid() bool {
+	return s == ServiceActive || s == ServiceInactive
+}
+
+// OrderBy is the sort order of GetOrdersRequest
+type OrderBy string
+
+const (
+	OrderByID             OrderBy = "ID"
+	OrderByOrderDate      OrderBy = "OrderDate"
+	OrderByUpdateDate     OrderBy = "UpdateDate"
+	OrderByExpirationDate OrderBy = "ExpirationDate"
+	OrderByCommonName     OrderBy = "CommonName"
+)
+
+// IsValid reports whether o is a known OrderBy
+func (o OrderBy) IsValid() bool {
+	switch o {
+	case OrderByID, OrderByOrderDate, OrderByUpdateDate, OrderByExpirationDate, OrderByCommonName:
+		return true
+	}
+	return false
+}
+
+// OrderDir is the sort direction of GetOrdersRequest
+type OrderDir string
+
+const (
+	OrderAscending  OrderDir = "ASC"
+	OrderDescending OrderDir = "DESC"
+)
+
+// IsValid reports whether d is a known OrderDir
+func (d OrderDir) IsValid() bool {
+	return d == OrderAscending || d == OrderDescending
+}
+
+// enum is implemented by the types above
+type enum interface {
+	IsValid() bool
+}
+
+var enumType = reflect.TypeOf((*enum)(nil)).Elem()
+
+// checkEnums walks a request before it is encoded and reports the first
+// field holding an unknown value. Empty values are left to the API.
+// Responses are never checked, so unknown values sent by the API are
+// tolerated.
+func checkEnums(request interface{}) error {
+	return walkEnums(reflect.ValueOf(request), "")
+}
+
+func walkEnums(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return walkEnums(v.Elem(), path)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := path
+			if !f.Anonymous {
+				name = joinFieldPath(path, f.Name)
+			}
+			if err := walkEnums(v.Field(i), name); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkEnums(v.Index(i), path); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if v.Type().Implements(enumType) && v.Len() > 0 && !v.Interface().(enum).IsValid() {
+			return FieldError{Key: path, Msg: fmt.Sprintf("unknown value %q", v.String())}
+		}
+	}
+	return nil
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
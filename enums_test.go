@@ -0,0 +1,92 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestUnknownEnumsAreRejected(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.DVAuthMethod = "EMIAL"
+	for _, tc := range []struct {
+		operation string
+		call      func() error
+		key       string
+	}{
+		{"GetOrders", func() error {
+			_, err := c.GetOrders(ctx, &certcenter.GetOrdersRequest{Status: []certcenter.MajorStatus{"COMPLTE"}})
+			return err
+		}, "Status"},
+		{"Order", func() error {
+			_, err := c.Order(ctx, order)
+			return err
+		}, "OrderParameters.DVAuthMethod"},
+		{"Revoke", func() error {
+			_, err := c.Revoke(ctx, &certcenter.RevokeRequest{CertCenterOrderID: 1, RevokeReason: "Key compromised"})
+			return err
+		}, "RevokeReason"},
+	} {
+		err := tc.call()
+		var fe certcenter.FieldError
+		if !errors.As(err, &fe) || fe.Key != tc.key || !errors.Is(err, certcenter.ErrInvalidRequest) {
+			t.Errorf("%s: got %v, want a FieldError for %s", tc.operation, err, tc.key)
+		}
+		if n := srv.Calls(tc.operation); n != 0 {
+			t.Errorf("%s reached the server %d times", tc.operation, n)
+		}
+	}
+}
+
+func TestUnknownEnumsAreReceived(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	id := placeOrder(t, srv, "www.example.com")
+	if err := srv.SetOrderStatus(id, "ARCHIVED", "", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := srv.Client().GetOrder(context.Background(), &certcenter.GetOrderRequest{CertCenterOrderID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := res.OrderInfo.OrderStatus.MajorStatus; status != "ARCHIVED" || status.IsValid() {
+		t.Errorf("got %q, want the unknown status kept", status)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{ IsValid() bool }
+		want  bool
+	}{
+		{certcenter.StatusComplete, true},
+		{certcenter.MajorStatus("ARCHIVED"), false},
+		{certcenter.ProductTypeSMIME, true},
+		{certcenter.ProductType("SSH"), false},
+		{certcenter.DVAuthDNS, true},
+		{certcenter.DVAuthMethod("HTTP"), false},
+		{certcenter.SignatureSHA256FullChain, true},
+		{certcenter.SignatureHashAlgorithm("MD5"), false},
+		{certcenter.ServiceActive, true},
+		{certcenter.ServiceStatus("active"), false},
+		{certcenter.RevokeKeyCompromise, true},
+		{certcenter.RevokeReason("Key compromised"), false},
+		{certcenter.OrderByExpirationDate, true},
+		{certcenter.OrderBy("EndDate"), false},
+		{certcenter.OrderAscending, true},
+		{certcenter.OrderDir("asc"), false},
+	} {
+		if got := tc.value.IsValid(); got != tc.want {
+			t.Errorf("%v.IsValid() = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
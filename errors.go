@@ -0,0 +1,148 @@
+package certcenter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is reported if the bearer token (or kv-storage key)
+	// is invalid or does not have the proper rights (HTTP 401/403)
+	ErrUnauthorized = errors.New("CertCenter API: Authorization failed")
+	// ErrNotFound is reported if the requested resource does not exist (HTTP 404)
+	ErrNotFound = errors.New("CertCenter API: Not found")
+	// ErrNoChanges is reported if a request did not change anything (HTTP 406)
+	ErrNoChanges = errors.New("CertCenter API: No changes made")
+	// ErrInvalidRequest is reported if the request data has been
+	// rejected, eg. due to scheme validation errors (HTTP 417)
+	ErrInvalidRequest = errors.New("CertCenter API: Invalid request data")
+)
+
+// FieldError describes a problem with a single request field, as
+// reported in SchemeValidationErrors
+type FieldError struct {
+	Key    string // dotted path of the field, eg. "OrderParameters.CSR"
+	Msg    string
+	Status string
+}
+
+func (e FieldError) Error() string {
+	if e.Key == "" {
+		return e.Msg
+	}
+	return e.Key + ": " + e.Msg
+}
+
+// Is makes FieldErrors returned before a request is sent match
+// ErrInvalidRequest, just like the API's validation errors
+func (e FieldError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// APIError is returned whenever the API responds with a non-200
+// status code or with "success": false. Use errors.Is to test for
+// ErrUnauthorized, ErrNotFound, ErrNoChanges or ErrInvalidRequest.
+type APIError struct {
+	StatusCode  int
+	ErrorId     int
+	ErrorField  string
+	Message     string
+	FieldErrors []FieldError
+
+	// Method and URL of the failed request
+	Method string
+	URL    string
+	// Body holds the raw response body
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	if e.StatusCode == http.StatusOK {
+		fmt.Fprintf(&b, "CertCenter API: %s %s failed", e.Method, e.URL)
+	} else {
+		fmt.Fprintf(&b, "CertCenter API: %s %s returned %d", e.Method, e.URL, e.StatusCode)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.ErrorId != 0 {
+		fmt.Fprintf(&b, " (ErrorId %d)", e.ErrorId)
+	}
+	if e.ErrorField != "" {
+		fmt.Fprintf(&b, " (ErrorField %s)", e.ErrorField)
+	}
+	for _, fe := range e.FieldErrors {
+		fmt.Fprintf(&b, "; %s", fe.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether e matches one of the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrNoChanges:
+		return e.StatusCode == http.StatusNotAcceptable
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusExpectationFailed ||
+			e.StatusCode == http.StatusBadRequest || len(e.FieldErrors) > 0
+	}
+	return false
+}
+
+// resultInfo is implemented by all results embedding BasicResultInfo
+type resultInfo interface {
+	basicResultInfo() *BasicResultInfo
+}
+
+func (b *BasicResultInfo) basicResultInfo() *BasicResultInfo {
+	return b
+}
+
+// newAPIError builds an APIError from a response and its decoded result
+func newAPIError(req *apiRequest, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: req.statusCode,
+		Method:     req.httpMethod,
+		URL:        req.url,
+		Body:       body,
+	}
+	if r, ok := req.result.(resultInfo); ok {
+		info := r.basicResultInfo()
+		e.ErrorId = info.ErrorId
+		e.ErrorField = info.ErrorField
+		e.Message = info.Message
+		if e.Message == "" {
+			e.Message = info.Msg
+		}
+		e.FieldErrors = info.SchemeValidationErrors.fieldErrors("")
+	}
+	if e.Message == "" && e.StatusCode != http.StatusOK {
+		e.Message = http.StatusText(e.StatusCode)
+	}
+	return e
+}
+
+// fieldErrors flattens nested scheme validation errors
+func (s SchemeValidationErrors) fieldErrors(prefix string) []FieldError {
+	var errs []FieldError
+	for _, e := range s.Errors {
+		key := e.Key
+		if prefix != "" && key != "" {
+			key = prefix + "." + key
+		} else if key == "" {
+			key = prefix
+		}
+		if e.Msg != "" {
+			errs = append(errs, FieldError{Key: key, Msg: e.Msg, Status: e.Status})
+		}
+		errs = append(errs, e.SchemeValidationErrors.fieldErrors(key)...)
+	}
+	return errs
+}
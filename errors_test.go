@@ -0,0 +1,129 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestAPIErrorNotFound(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client().GetOrder(context.Background(), &certcenter.GetOrderRequest{CertCenterOrderID: 404})
+	var apiErr *certcenter.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %T %v, want an *APIError", err, err)
+	}
+	if !errors.Is(err, certcenter.ErrNotFound) || errors.Is(err, certcenter.ErrInvalidRequest) {
+		t.Errorf("%v should only match ErrNotFound", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.ErrorId != -1001 || apiErr.ErrorField != "CertCenterOrderID" ||
+		apiErr.Message != "Order not found" || apiErr.Method != "GET" || !strings.Contains(apiErr.URL, "/Order/404?") ||
+		len(apiErr.Body) == 0 {
+		t.Errorf("got %+v", apiErr)
+	}
+}
+
+func TestAPIErrorFieldErrors(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.ProductCode = "Example.Unknown"
+	_, err := srv.Client().Order(context.Background(), order)
+	if !errors.Is(err, certcenter.ErrInvalidRequest) {
+		t.Fatalf("got %v, want ErrInvalidRequest", err)
+	}
+	var apiErr *certcenter.APIError
+	errors.As(err, &apiErr)
+	if apiErr.StatusCode != http.StatusExpectationFailed || apiErr.Method != "POST" || len(apiErr.FieldErrors) != 1 {
+		t.Fatalf("got %+v", apiErr)
+	}
+	if fe := apiErr.FieldErrors[0]; fe.Key != "OrderParameters.ProductCode" || fe.Msg != "Unknown ProductCode" {
+		t.Errorf("got %+v", fe)
+	}
+	if !strings.Contains(err.Error(), "OrderParameters.ProductCode: Unknown ProductCode") {
+		t.Errorf("%q doesn't mention the field", err)
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, certcenter.ErrUnauthorized},
+		{http.StatusForbidden, certcenter.ErrUnauthorized},
+		{http.StatusNotFound, certcenter.ErrNotFound},
+		{http.StatusNotAcceptable, certcenter.ErrNoChanges},
+		{http.StatusExpectationFailed, certcenter.ErrInvalidRequest},
+		{http.StatusBadRequest, certcenter.ErrInvalidRequest},
+	} {
+		srv.FailNext("Limit", certcentertest.Failure{StatusCode: tc.status})
+		if _, err := c.Limit(ctx); !errors.Is(err, tc.want) {
+			t.Errorf("%d: got %v, want %v", tc.status, err, tc.want)
+		}
+	}
+
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusInternalServerError})
+	_, err := c.Limit(ctx)
+	for _, sentinel := range []error{certcenter.ErrUnauthorized, certcenter.ErrNotFound, certcenter.ErrNoChanges, certcenter.ErrInvalidRequest} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("500 matches %v", sentinel)
+		}
+	}
+}
+
+func TestAPIErrorUnsuccessful(t *testing.T) {
+	// some endpoints report failures with HTTP 200
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":false,"ErrorId":-2010,"Message":"Insufficient limit"}`))
+	}))
+	defer ts.Close()
+
+	// nothing is printed to stdout
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	_, err = certcenter.NewClient(certcenter.WithBaseURL(ts.URL)).Limit(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	if printed, _ := ioutil.ReadAll(r); len(printed) > 0 {
+		t.Errorf("printed %q", printed)
+	}
+
+	var apiErr *certcenter.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusOK || apiErr.ErrorId != -2010 || apiErr.Message != "Insufficient limit" {
+		t.Errorf("got %+v", apiErr)
+	}
+}
+
+func TestFieldErrorIsInvalidRequest(t *testing.T) {
+	err := error(certcenter.FieldError{Key: "OrderParameters.CSR", Msg: "CSR is required"})
+	if !errors.Is(err, certcenter.ErrInvalidRequest) {
+		t.Error("FieldError should match ErrInvalidRequest")
+	}
+	if err.Error() != "OrderParameters.CSR: CSR is required" {
+		t.Errorf("got %q", err)
+	}
+}
@@ -18,8 +18,8 @@ func main() {
 	// Get filtered orders
 	//
 	res, _ := certcenter.GetOrders(&certcenter.GetOrdersRequest{
-		Status:                   "COMPLETE", // COMPLETE, PENDING, CANCELLED, REVOKED
-		ProductType:              "SSL",      // SSL, CODESIGN, SMIME, TRUSTSEAL
+		Status:                   []certcenter.MajorStatus{"COMPLETE"}, // COMPLETE, PENDING, CANCELLED, REVOKED
+		ProductType:              []certcenter.ProductType{"SSL"},      // SSL, CODESIGN, SMIME, TRUSTSEAL
 		CommonName:               "%",
 		IncludeFulfillment:       false,
 		IncludeOrderParameters:   true,
@@ -20,7 +20,7 @@ func main() {
 	res, _ := certcenter.Revoke(&certcenter.RevokeRequest{
 		CertCenterOrderID: 123456789,
 		// Optional parameters
-		RevokeReason: "Key compromised",
+		RevokeReason: certcenter.RevokeKeyCompromise,
 		Certificate:  "#PEM-encoded-X.509-Certificate#",
 	})
 	fmt.Println(res)
@@ -1,137 +1,124 @@
 package certcenter
 
 import (
+	"bytes"
 	"certcenter.com/go/query"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"reflect"
+	"time"
 )
 
-// do is the central API communication handler
+// do is the central API communication handler. The request is bound
+// to ctx, so cancelling ctx aborts it.
 //
-func (req *apiRequest) do(apiMethod string, ParamType ...int) error {
+func (req *apiRequest) do(ctx context.Context, operation string) error {
 
-	var postData io.Reader
-	paramType := CC_PARAM_TYPE_QS
-	anonymously := false
-	req.httpMethod = "GET"
-	req.method = apiMethod
-	rawURL := "https://api.certcenter.com/rest/v1/"
-	req.url = rawURL + req.method
-	req.client = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				PreferServerCipherSuites: true,
-				MinVersion:               tls.VersionTLS12,
-			},
-		},
-	}
-
-	if len(ParamType) > 0 {
-		paramType = ParamType[0]
-		switch paramType {
-		case CC_PARAM_TYPE_QS:
-			v, err := query.Values(req.request)
-			if err != nil {
-				return err
-			}
+	ep, ok := endpoints[operation]
+	if !ok {
+		return fmt.Errorf("CertCenter API: Unknown operation %q", operation)
+	}
+	req.method = operation
+	req.httpMethod = ep.method
+
+	if err := checkEnums(req.request); err != nil {
+		return err
+	}
+	if req.client.validate {
+		if err := req.client.validateRequest(ctx, req.request); err != nil {
+			return err
+		}
+	}
+
+	var params map[string]pathParam
+	path := ep.path
+	if ep.params&CC_PARAM_TYPE_PATH != 0 {
+		params = pathParams(req.request)
+		var err error
+		if path, err = expandPath(ep.path, params); err != nil {
+			return err
+		}
+	}
+	req.url = joinURL(req.client.baseURL, path)
+
+	if ep.params&CC_PARAM_TYPE_QS != 0 {
+		v, err := query.Values(req.request)
+		if err != nil {
+			return err
+		}
+		for _, p := range params {
+			v.Del(p.queryKey)
+		}
+		if len(v) > 0 {
 			req.url += "?" + v.Encode()
-		case CC_PARAM_TYPE_BODY:
-			req.httpMethod = "POST"
-			d, err := json.Marshal(req.request)
-			if err != nil {
-				return err
-			}
-			postData = strings.NewReader(string(d))
-		case CC_PARAM_TYPE_PATH:
-			if apiMethod == "ApproverEmail" {
-				req.httpMethod = "POST"
-				req.url = fmt.Sprintf("%s/%d", req.url,
-					req.request.(*ResendApproverEmailRequest).CertCenterOrderID)
-			} else if apiMethod == "Order" {
-				req.httpMethod = "DELETE"
-				req.url = fmt.Sprintf("%s/%d", req.url,
-					req.request.(*DeleteOrderRequest).CertCenterOrderID)
-			} else if apiMethod == "VulnerabilityAssessment" {
-				req.url = fmt.Sprintf("%s/%d", req.url,
-					req.request.(*VulnerabilityAssessmentRequest).CertCenterOrderID)
-				} else if apiMethod == "User" {
-					req.url = fmt.Sprintf("%s/%s", req.url, req.request.(*GetUserRequest).UsernameOrUserId)
-			} else if apiMethod == "BaseDomain" {
-				req.url = fmt.Sprintf("%s/%s", req.url, req.request.(*BaseDomainRequest).FQDN)
-			} else if apiMethod == "DeleteUser" {
-				apiMethod = "User"
-				req.httpMethod = "DELETE"
-				req.url = fmt.Sprintf("%sUser/%s", rawURL, req.request.(*DeleteUserRequest).UsernameOrUserId)
-			} else if apiMethod == "GetVoucher" {
-				apiMethod = "Voucher"
-				req.url = fmt.Sprintf("%sVoucher/%s", rawURL, req.request.(*GetVoucherRequest).VoucherCode)
-			} else if apiMethod == "GetVoucherAnonymously" {
-				apiMethod = "Voucher"
-				req.url = fmt.Sprintf("%sVoucher/*/%s", rawURL, req.request.(*GetVoucherRequest).VoucherCode)
-			} else if apiMethod == "DeleteVoucher" {
-				apiMethod = "Voucher"
-				req.httpMethod = "DELETE"
-				req.url = fmt.Sprintf("%sVoucher/%s", rawURL, req.request.(*DeleteVoucherRequest).VoucherCode)
-			} else if apiMethod == "GetVoucherOrderAnonymously" {
-				apiMethod = "Order"
-				req.url = fmt.Sprintf("%sOrder/*/%s", req.url, req.request.(*GetVoucherRequest).VoucherCode)
-			}
-		case CC_PARAM_TYPE_QS | CC_PARAM_TYPE_PATH:
-			if apiMethod == "ApproverEmail" {
-				req.httpMethod = "PUT"
-				req.url = fmt.Sprintf("%s/%d?ApproverEmail=%s", req.url,
-					req.request.(*PutApproverEmailRequest).CertCenterOrderID,
-					req.request.(*PutApproverEmailRequest).ApproverEmail)
-			} else if apiMethod == "Order" {
-				v, err := query.Values(req.request)
-				if err != nil {
-					return err
-				}
-				req.url += fmt.Sprintf("/%d?", req.request.(*GetOrderRequest).CertCenterOrderID)
-				x := v.Encode()
-				req.url += x
-			}
-		case CC_PARAM_TYPE_BODY | CC_PARAM_TYPE_PATH:
-			if apiMethod == "Revoke" {
-				req.httpMethod = "DELETE"
-				req.url = fmt.Sprintf("%s/%d", req.url,
-					req.request.(*RevokeRequest).CertCenterOrderID)
-			} else if apiMethod == "User" {
-				req.httpMethod = "POST"
-				req.url = fmt.Sprintf("%s/%s", req.url, req.request.(*UpdateUserRequest).UsernameOrUserId)
-				req.request.(*UpdateUserRequest).UsernameOrUserId = ""
-			}
-			d, err := json.Marshal(req.request)
-			if err != nil {
-				return err
-			}
-			postData = strings.NewReader(string(d))
 		}
 	}
 
-	request, err := http.NewRequest(req.httpMethod, req.url, postData)
+	var body []byte
+	if ep.params&CC_PARAM_TYPE_BODY != 0 {
+		var err error
+		if body, err = encodeBody(req.request, params); err != nil {
+			return err
+		}
+	}
+
+	hold, err := req.client.budget.hold(ctx, operation, req.request)
 	if err != nil {
 		return err
 	}
+	started := time.Now()
+	err = req.client.retry(ctx, req, func() error {
+		return req.send(ctx, ep, body)
+	})
+	hold.settle(ctx, err, req.request, started)
+	return err
+}
 
-	if !anonymously {
-		request.Header.Add("Authorization", "Bearer "+Bearer)
+// send performs a single attempt of a prepared request
+func (req *apiRequest) send(ctx context.Context, ep endpoint, body []byte) error {
+
+	// start from scratch, a previous attempt may have left some fields
+	if v := reflect.ValueOf(req.result); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+	req.retryAfter = 0
+
+	var postData io.Reader
+	if body != nil {
+		postData = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, req.httpMethod, req.url, postData)
+	if err != nil {
+		return err
+	}
+
+	if !ep.anonymous {
+		request.Header.Add("Authorization", "Bearer "+req.client.bearerToken())
 	}
 	request.Header.Set("Content-Type", "application/json; charset=utf8")
+	if req.client.userAgent != "" {
+		request.Header.Set("User-Agent", req.client.userAgent)
+	}
 
-	response, err := req.client.Do(request)
+	release, err := req.client.limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	response, err := req.client.httpClient.Do(request)
 	if err != nil {
 		return err
 	}
 	defer response.Body.Close()
 
-	if response.ContentLength > 1<<24 || response.ContentLength == 0 {
+	if response.ContentLength > 1<<24 || (response.ContentLength == 0 && response.StatusCode == 200) {
 		return errors.New("CertCenter API: Returned content with wired length")
 	}
 
@@ -142,20 +129,21 @@ func (req *apiRequest) do(apiMethod string, ParamType ...int) error {
 
 	req.statusCode = response.StatusCode
 	if response.StatusCode != 200 {
-		switch response.StatusCode {
-		default:
-			return fmt.Errorf("CertCenter API: Returned with Status %d", response.StatusCode)
-		case 401:
-			return fmt.Errorf("CertCenter API: Autorization failed. Used bearer token is invalid or does not have the proper rights")
-		case 417: // Invalid Request Data
-		case 406: // No Changes Made
-		}
+		req.retryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+		// 417 (Invalid Request Data), 406 (No Changes Made) and others
+		// usually carry details in a regular result structure
+		json.Unmarshal(data, &req.result)
+		return newAPIError(req, data)
 	}
 
 	if err := json.Unmarshal(data, &req.result); err != nil {
 		return err
 	}
 
+	if r, ok := req.result.(resultInfo); ok && !r.basicResultInfo().Success {
+		return newAPIError(req, data)
+	}
+
 	return nil
 }
 
@@ -163,43 +151,49 @@ func (req *apiRequest) do(apiMethod string, ParamType ...int) error {
 // with AlwaysOnSSL (aka DigiCert Encryption Everywhere) certificates as
 // described at https://developers.certcenter.com/docs/tutorial-integrate-alwaysonssl
 //
-func (req *apiRequest) kv() error {
+func (req *apiRequest) kv(ctx context.Context) error {
 
-	if KvStoreAuthorizationKey == "" {
+	kvKey := req.client.kvStoreAuthorizationKey()
+	if kvKey == "" {
 		return errors.New("KvStoreAuthorizationKey not set. See https://developers.certcenter.com/v1/docs/file-validation-mod-fauth for more details.")
 	}
 
-	req.url = "https://fauth-db.eu.certcenter.com/" + req.request.(*KeyValueStoreRequest).Key
-	req.request.(*KeyValueStoreRequest).Key = ""
-	req.client = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				PreferServerCipherSuites: true,
-				MinVersion:               tls.VersionTLS12,
-			},
-		},
+	params := pathParams(req.request)
+	path, err := expandPath("{Key}", params)
+	if err != nil {
+		return err
 	}
+	req.url = joinURL(req.client.kvURL, path)
 
-	d, err := json.Marshal(req.request)
+	d, err := encodeBody(req.request, params)
 	if err != nil {
 		return err
 	}
 
-	request, err := http.NewRequest("POST", req.url, strings.NewReader(string(d)))
+	request, err := http.NewRequestWithContext(ctx, "POST", req.url, bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
 
-	request.Header.Add("x-api-key", KvStoreAuthorizationKey)
+	request.Header.Add("x-api-key", kvKey)
 	request.Header.Add("Content-Type", "application/json")
+	if req.client.userAgent != "" {
+		request.Header.Set("User-Agent", req.client.userAgent)
+	}
 
-	response, err := req.client.Do(request)
+	release, err := req.client.limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	response, err := req.client.httpClient.Do(request)
 	if err != nil {
 		return err
 	}
 	defer response.Body.Close()
 
-	if response.ContentLength > 1<<12 || response.ContentLength == 0 {
+	if response.ContentLength > 1<<12 || (response.ContentLength == 0 && response.StatusCode == 200) {
 		return errors.New("CertCenter KV-API: Returned content with wired length")
 	}
 
@@ -208,9 +202,15 @@ func (req *apiRequest) kv() error {
 		return err
 	}
 
+	req.httpMethod = request.Method
 	req.statusCode = response.StatusCode
 	if response.StatusCode != 200 {
-		return errors.New("CertCenter KV-API: Invalid status code")
+		e := newAPIError(req, data)
+		var result KeyValueStoreResult
+		if json.Unmarshal(data, &result) == nil && result.Message != "" {
+			e.Message = result.Message
+		}
+		return e
 	}
 
 	if err := json.Unmarshal(data, &req.result); err != nil {
@@ -0,0 +1,43 @@
+package certcenter_test
+
+import (
+	"context"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+)
+
+// newCSR returns a PEM-encoded CSR for commonName
+func newCSR(t *testing.T, commonName string) string {
+	t.Helper()
+	pemCSR, _, err := csr.Generate(&csr.Request{CommonName: commonName}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemCSR
+}
+
+// newOrder returns an order request for a PositiveSSL certificate
+func newOrder(t *testing.T, commonName, partnerOrderID string) *certcenter.OrderRequest {
+	t.Helper()
+	return &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            newCSR(t, commonName),
+			ValidityPeriod: 12,
+			PartnerOrderID: partnerOrderID,
+		},
+	}
+}
+
+// placeOrder orders a certificate for commonName on srv
+func placeOrder(t *testing.T, srv *certcentertest.Server, commonName string) int64 {
+	t.Helper()
+	res, err := srv.Client().Order(context.Background(), newOrder(t, commonName, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.CertCenterOrderID
+}
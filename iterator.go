@@ -0,0 +1,202 @@
+package certcenter
+
+import (
+	"context"
+)
+
+// DefaultPageSize is the number of orders an OrdersIterator requests
+// per page unless configured otherwise
+const DefaultPageSize = 100
+
+// IteratorOption configures an OrdersIterator
+type IteratorOption func(*OrdersIterator)
+
+// WithPageSize sets the number of orders requested per page. It takes
+// precedence over GetOrdersRequest.ItemsPerPage.
+func WithPageSize(n int64) IteratorOption {
+	return func(it *OrdersIterator) {
+		it.request.ItemsPerPage = n
+	}
+}
+
+// WithPrefetch makes the iterator fetch up to n pages ahead in the
+// background while the current page is being consumed
+func WithPrefetch(n int) IteratorOption {
+	return func(it *OrdersIterator) {
+		it.prefetch = n
+	}
+}
+
+// OrdersIterator walks all pages of a GetOrders query:
+//
+//	it := client.IterateOrders(ctx, &certcenter.GetOrdersRequest{Status: []certcenter.MajorStatus{certcenter.StatusComplete}})
+//	defer it.Close()
+//	for it.Next() {
+//		order := it.Order()
+//		..
+//	}
+//	if err := it.Err(); err != nil {
+//		..
+//	}
+//
+// Orders which move to a later page while iterating (eg. because new
+// orders have been placed meanwhile) are returned only once.
+type OrdersIterator struct {
+	client   *Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	request  GetOrdersRequest
+	prefetch int
+
+	started bool
+	closed  bool
+	pages   chan ordersPage
+	last    ordersPage
+	pos     int
+	order   OrderInfo
+	seen    map[int64]bool
+	err     error
+}
+
+// ordersPage is a single response of the iterator's query
+type ordersPage struct {
+	result *GetOrdersResult
+	err    error
+	final  bool
+}
+
+// IterateOrders returns an iterator over all orders matching request,
+// starting at request.Page (or the first page)
+func (c *Client) IterateOrders(ctx context.Context, request *GetOrdersRequest, options ...IteratorOption) *OrdersIterator {
+	it := &OrdersIterator{
+		client: c,
+		seen:   make(map[int64]bool),
+	}
+	if request != nil {
+		it.request = *request
+	}
+	if it.request.Page < 1 {
+		it.request.Page = 1
+	}
+	if it.request.ItemsPerPage < 1 {
+		it.request.ItemsPerPage = DefaultPageSize
+	}
+	for _, option := range options {
+		option(it)
+	}
+	it.ctx, it.cancel = context.WithCancel(ctx)
+	return it
+}
+
+// Next advances to the next order. It returns false when all orders
+// have been visited or an error occurred (see Err).
+func (it *OrdersIterator) Next() bool {
+	if !it.started {
+		it.start()
+	}
+	for {
+		if it.err != nil || it.closed {
+			return false
+		}
+		if it.last.result != nil && it.pos < len(it.last.result.OrderInfos) {
+			order := it.last.result.OrderInfos[it.pos]
+			it.pos++
+			if it.seen[order.CertCenterOrderID] {
+				continue
+			}
+			it.seen[order.CertCenterOrderID] = true
+			it.order = order
+			return true
+		}
+		if it.last.final {
+			it.Close()
+			return false
+		}
+		page, ok := it.nextPage()
+		if !ok {
+			// the producer gave up because the context is done
+			it.err = it.ctx.Err()
+			return false
+		}
+		it.last, it.pos = page, 0
+		it.err = page.err
+	}
+}
+
+// Order returns the current order
+func (it *OrdersIterator) Order() OrderInfo {
+	return it.order
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *OrdersIterator) Err() error {
+	return it.err
+}
+
+// Total returns the number of matching orders reported by the most
+// recent page (ItemsAvailable), or -1 before the first page arrived
+func (it *OrdersIterator) Total() int64 {
+	if it.last.result == nil {
+		return -1
+	}
+	return it.last.result.Meta.ItemsAvailable
+}
+
+// Close ends the iteration and stops fetching further pages. It only
+// needs to be called if the iteration is abandoned before Next returned false.
+func (it *OrdersIterator) Close() {
+	it.closed = true
+	it.cancel()
+}
+
+// start launches the goroutine fetching pages ahead, if prefetch is enabled
+func (it *OrdersIterator) start() {
+	it.started = true
+	if it.prefetch < 1 {
+		return
+	}
+	// the producer holds one more page while blocked on the channel
+	it.pages = make(chan ordersPage, it.prefetch-1)
+	go func() {
+		defer close(it.pages)
+		request := it.request
+		for {
+			page := it.fetch(&request)
+			select {
+			case it.pages <- page:
+			case <-it.ctx.Done():
+				return
+			}
+			if page.err != nil || page.final {
+				return
+			}
+			request.Page++
+		}
+	}()
+}
+
+// nextPage returns the next page, either prefetched or requested now
+func (it *OrdersIterator) nextPage() (ordersPage, bool) {
+	if it.pages != nil {
+		page, ok := <-it.pages
+		return page, ok
+	}
+	page := it.fetch(&it.request)
+	it.request.Page++
+	return page, true
+}
+
+// fetch requests a single page and decides whether it's the last one
+func (it *OrdersIterator) fetch(request *GetOrdersRequest) ordersPage {
+	result, err := it.client.GetOrders(it.ctx, request)
+	if err != nil {
+		return ordersPage{err: err}
+	}
+	perPage := result.Meta.ItemsPerPage
+	if perPage < 1 {
+		perPage = request.ItemsPerPage
+	}
+	final := int64(len(result.OrderInfos)) < perPage ||
+		(result.Meta.ItemsAvailable > 0 && request.Page*perPage >= result.Meta.ItemsAvailable)
+	return ordersPage{result: result, final: final}
+}
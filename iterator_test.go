@@ -0,0 +1,81 @@
+package certcenter_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestIterateOrders(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	for i := 0; i < 25; i++ {
+		placeOrder(t, srv, "www.example.com")
+	}
+
+	for _, prefetch := range []int{0, 1, 3} {
+		calls := srv.Calls("GetOrders")
+		it := c.IterateOrders(context.Background(), nil, certcenter.WithPageSize(10), certcenter.WithPrefetch(prefetch))
+		seen := make(map[int64]bool)
+		for it.Next() {
+			id := it.Order().CertCenterOrderID
+			if seen[id] {
+				t.Errorf("prefetch %d: order %d returned twice", prefetch, id)
+			}
+			seen[id] = true
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("prefetch %d: %v", prefetch, err)
+		}
+		if len(seen) != 25 || it.Total() != 25 {
+			t.Errorf("prefetch %d: got %d orders (total %d), want 25", prefetch, len(seen), it.Total())
+		}
+		if n := srv.Calls("GetOrders") - calls; n != 3 {
+			t.Errorf("prefetch %d: got %d pages, want 3", prefetch, n)
+		}
+	}
+}
+
+func TestIterateOrdersError(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 5; i++ {
+		placeOrder(t, srv, "www.example.com")
+	}
+
+	// the first page succeeds, the second one fails
+	it := srv.Client().IterateOrders(context.Background(), nil, certcenter.WithPageSize(3))
+	n := 0
+	for it.Next() {
+		if n++; n == 3 {
+			srv.FailNext("GetOrders", certcentertest.Failure{StatusCode: http.StatusBadRequest})
+		}
+	}
+	if n != 3 || it.Err() == nil {
+		t.Errorf("got %d orders and error %v, want 3 and an error", n, it.Err())
+	}
+}
+
+func TestIterateOrdersClose(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 5; i++ {
+		placeOrder(t, srv, "www.example.com")
+	}
+
+	it := srv.Client().IterateOrders(context.Background(), nil, certcenter.WithPageSize(2))
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	it.Close()
+	if it.Next() {
+		t.Error("Next returned true after Close")
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("got %v after Close, want nil", err)
+	}
+}
@@ -0,0 +1,151 @@
+package certcenter
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CSRDetails is the result of ParseCSR. It carries the same ParsedCSR
+// the API returns from ValidateCSR, plus the SubjectAltNames.
+type CSRDetails struct {
+	ParsedCSR
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []string
+	// Request is the decoded PKCS#10
+	Request *x509.CertificateRequest
+}
+
+// ParseCSR decodes a PEM-encoded PKCS#10 locally, without calling
+// ValidateCSR. The hashes are calculated over the DER encoding, like
+// the API does. UniqueValue is left empty, as only the API knows how
+// it is derived.
+func ParseCSR(csr string) (*CSRDetails, error) {
+	block, _ := pem.Decode([]byte(csr))
+	if block == nil || !strings.HasSuffix(block.Type, "CERTIFICATE REQUEST") {
+		return nil, errors.New("certcenter: no PEM-encoded PKCS#10 found")
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &CSRDetails{
+		DNSNames:       req.DNSNames,
+		EmailAddresses: req.EmailAddresses,
+		Request:        req,
+	}
+	for _, ip := range req.IPAddresses {
+		d.IPAddresses = append(d.IPAddresses, ip.String())
+	}
+	p := &d.ParsedCSR
+	p.CommonName = req.Subject.CommonName
+	p.Organization = firstValue(req.Subject.Organization)
+	p.OrganizationUnit = firstValue(req.Subject.OrganizationalUnit)
+	p.Email = firstValue(req.EmailAddresses)
+	p.State = firstValue(req.Subject.Province)
+	p.Locality = firstValue(req.Subject.Locality)
+	p.Country = firstValue(req.Subject.Country)
+	p.KeyLength = publicKeyLength(req.PublicKey)
+	p.SignaturAlgorithm = req.SignatureAlgorithm.String()
+	p.KeyEncryptionAlgorithm = req.PublicKeyAlgorithm.String()
+	md5Sum, sha256Sum := md5.Sum(req.Raw), sha256.Sum256(req.Raw)
+	p.HashMD5 = strings.ToUpper(hex.EncodeToString(md5Sum[:]))
+	p.HashSHA256 = strings.ToUpper(hex.EncodeToString(sha256Sum[:]))
+	return d, nil
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func publicKeyLength(pub interface{}) int {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+// Weaknesses lists the reasons a CA would likely reject the CSR: RSA
+// keys below 2048 bits, EC keys below 256 bits, unsupported key types,
+// MD5 or SHA-1 signatures and broken self-signatures
+func (d *CSRDetails) Weaknesses() []string {
+	var weaknesses []string
+	switch d.Request.PublicKeyAlgorithm {
+	case x509.RSA:
+		if d.KeyLength < 2048 {
+			weaknesses = append(weaknesses, fmt.Sprintf("RSA key of %d bits is too weak (min. 2048)", d.KeyLength))
+		}
+	case x509.ECDSA:
+		if d.KeyLength < 256 {
+			weaknesses = append(weaknesses, fmt.Sprintf("EC key of %d bits is too weak (min. 256)", d.KeyLength))
+		}
+	default:
+		weaknesses = append(weaknesses, fmt.Sprintf("%s keys are not supported", d.Request.PublicKeyAlgorithm))
+	}
+	switch d.Request.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		weaknesses = append(weaknesses, fmt.Sprintf("%s signatures are too weak", d.Request.SignatureAlgorithm))
+	default:
+		if err := d.Request.CheckSignature(); err != nil {
+			weaknesses = append(weaknesses, fmt.Sprintf("invalid signature: %v", err))
+		}
+	}
+	return weaknesses
+}
+
+// Names returns the CommonName (if any) and the DNS names of the CSR,
+// without duplicates
+func (d *CSRDetails) Names() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{d.CommonName}, d.DNSNames...) {
+		key := strings.ToLower(name)
+		if name != "" && !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// MismatchedNames compares the CSR with the SubjectAltNames of an
+// order. missing are ordered but not requested by the CSR, extra are
+// requested by the CSR but not ordered. The CommonName is never extra.
+func (d *CSRDetails) MismatchedNames(subjectAltNames []string) (missing, extra []string) {
+	csrNames := make(map[string]bool)
+	for _, name := range d.Names() {
+		csrNames[strings.ToLower(name)] = true
+	}
+	ordered := make(map[string]bool)
+	for _, name := range subjectAltNames {
+		name = strings.TrimSpace(name)
+		ordered[strings.ToLower(name)] = true
+		if name != "" && !csrNames[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	for _, name := range d.DNSNames {
+		if !ordered[strings.ToLower(name)] && !strings.EqualFold(name, d.CommonName) {
+			extra = append(extra, name)
+		}
+	}
+	return missing, extra
+}
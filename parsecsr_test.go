@@ -0,0 +1,165 @@
+package certcenter_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+// rawCSR builds a CSR with key, bypassing package csr so that weak
+// keys and broken signatures can be tested
+func rawCSR(t *testing.T, key crypto.Signer, template *x509.CertificateRequest) (string, []byte) {
+	t.Helper()
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), der
+}
+
+func TestParseCSR(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   "www.example.com",
+			Organization: []string{"Example Inc."},
+			Country:      []string{"DE"},
+		},
+		DNSNames: []string{"www.example.com", "example.com"},
+	}
+
+	for _, tc := range []struct {
+		key       crypto.Signer
+		keyLength int
+		algorithm string
+	}{
+		{rsaKey, 2048, "RSA"},
+		{ecKey, 384, "ECDSA"},
+	} {
+		pemCSR, der := rawCSR(t, tc.key, template)
+		d, err := certcenter.ParseCSR(pemCSR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(der)
+		if d.CommonName != "www.example.com" || d.Organization != "Example Inc." || d.Country != "DE" ||
+			d.KeyLength != tc.keyLength || d.KeyEncryptionAlgorithm != tc.algorithm ||
+			d.HashSHA256 != strings.ToUpper(hex.EncodeToString(sum[:])) || d.UniqueValue != "" {
+			t.Errorf("%s: got %+v", tc.algorithm, d.ParsedCSR)
+		}
+		if len(d.DNSNames) != 2 {
+			t.Errorf("%s: got SANs %v", tc.algorithm, d.DNSNames)
+		}
+		if w := d.Weaknesses(); len(w) > 0 {
+			t.Errorf("%s: unexpected weaknesses %v", tc.algorithm, w)
+		}
+	}
+}
+
+func TestParseCSRMatchesValidateCSR(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	pemCSR := newCSR(t, "www.example.com")
+
+	local, err := certcenter.ParseCSR(pemCSR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := srv.Client().ValidateCSR(context.Background(), &certcenter.ValidateCSRRequest{CSR: pemCSR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local.ParsedCSR != remote.ParsedCSR {
+		t.Errorf("ParseCSR returned %+v, ValidateCSR %+v", local.ParsedCSR, remote.ParsedCSR)
+	}
+}
+
+func TestParseCSRErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"not a CSR",
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte{1, 2, 3}})),
+	} {
+		if _, err := certcenter.ParseCSR(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestCSRWeaknesses(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCSR, _ := rawCSR(t, weak, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}})
+	d, err := certcenter.ParseCSR(pemCSR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := d.Weaknesses(); len(w) != 1 || !strings.Contains(w[0], "1024") {
+		t.Errorf("got %v, want the weak key reported", w)
+	}
+
+	// flip a bit of the signature
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, der := rawCSR(t, key, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}})
+	der[len(der)-3] ^= 0xff
+	d, err = certcenter.ParseCSR(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := d.Weaknesses(); len(w) != 1 || !strings.Contains(w[0], "signature") {
+		t.Errorf("got %v, want the broken signature reported", w)
+	}
+}
+
+func TestCSRMismatchedNames(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCSR, _ := rawCSR(t, key, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "www.example.com"},
+		DNSNames: []string{"www.example.com", "mail.example.com"},
+	})
+	d, err := certcenter.ParseCSR(pemCSR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, extra := d.MismatchedNames([]string{"WWW.example.com", "shop.example.com"})
+	if len(missing) != 1 || missing[0] != "shop.example.com" || len(extra) != 1 || extra[0] != "mail.example.com" {
+		t.Errorf("got missing %v, extra %v", missing, extra)
+	}
+
+	// local validation rejects the order
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.CSR = pemCSR
+	order.OrderParameters.SubjectAltNames = []string{"www.example.com", "shop.example.com"}
+	if err := order.Validate(); err == nil {
+		t.Error("expected mismatched SubjectAltNames to fail validation")
+	}
+}
@@ -0,0 +1,201 @@
+package certcenter
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Estimate is a price calculated locally from a product's details. The
+// API doesn't document its pricing, so estimates assume that
+//
+//   - the Price covers the first year and Licenses servers,
+//   - SANs are charged per SANPackageSize at SANPackagePrice, or
+//     per host at SANHostPrice if there are no packages,
+//   - every additional server costs another Price and
+//   - every year started costs the same (AlwaysOnSSL validity is in days).
+//
+// Use Client.CheckQuote to compare an estimate with the live Quote.
+type Estimate struct {
+	ProductCode string
+	Currency    string
+	// Years is the number of billed years
+	Years int
+	// Base, SANs and Servers break down the price of a single year
+	Base    float64
+	SANs    float64
+	Servers float64
+	// Price is the total
+	Price float64
+}
+
+// EstimatePrice calculates the price of product for request without
+// asking the API. request.ProductCode is ignored, a ValidityPeriod of 0
+// means 12 months. Requests exceeding the product's limits return
+// ValidationErrors.
+func EstimatePrice(product *ProductInfo, request *QuoteRequest) (*Estimate, error) {
+	v := new(validation)
+	validity := request.ValidityPeriod
+	alwaysOnSSL := product.Flags().AlwaysOnSSL
+	if validity == 0 {
+		validity = 12
+		if alwaysOnSSL {
+			validity = product.MaxValidityPeriod
+		}
+	}
+	if validity < 0 {
+		v.add("ValidityPeriod", "must not be negative")
+	} else if product.MaxValidityPeriod > 0 && validity > product.MaxValidityPeriod {
+		v.add("ValidityPeriod", "exceeds the maximum of %d", product.MaxValidityPeriod)
+	}
+	if request.SubjectAltNameCount < 0 {
+		v.add("SubjectAltNameCount", "must not be negative")
+	} else if request.SubjectAltNameCount > product.SANMaxHosts {
+		v.add("SubjectAltNameCount", "%s supports up to %d SubjectAltNames", product.ProductCode, product.SANMaxHosts)
+	}
+	if request.ServerCount < 0 {
+		v.add("ServerCount", "must not be negative")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	e := &Estimate{
+		ProductCode: product.ProductCode,
+		Currency:    product.Currency,
+		Years:       1,
+		Base:        product.Price,
+	}
+	if !alwaysOnSSL && validity > 12 {
+		e.Years = (validity + 11) / 12
+	}
+	if sans := request.SubjectAltNameCount; sans > 0 {
+		if product.SANPackageSize > 0 {
+			packages := (sans + product.SANPackageSize - 1) / product.SANPackageSize
+			e.SANs = float64(packages) * product.SANPackagePrice
+		} else {
+			e.SANs = float64(sans) * product.SANHostPrice
+		}
+	}
+	if product.Licenses > 0 && request.ServerCount > product.Licenses {
+		e.Servers = float64(request.ServerCount-product.Licenses) * product.Price
+	}
+	e.Price = roundPrice((e.Base + e.SANs + e.Servers) * float64(e.Years))
+	return e, nil
+}
+
+// roundPrice rounds to cents
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// QuoteCheck compares an Estimate with the live Quote
+type QuoteCheck struct {
+	Estimate *Estimate
+	Quote    *QuoteResult
+	// Difference is Quote.Price - Estimate.Price
+	Difference float64
+}
+
+// Matches reports whether the quote is within tolerance of the estimate
+// and in the same currency
+func (q *QuoteCheck) Matches(tolerance float64) bool {
+	return strings.EqualFold(q.Quote.Currency, q.Estimate.Currency) &&
+		math.Abs(q.Difference) <= tolerance
+}
+
+// CheckQuote estimates the price of request locally and fetches the
+// live Quote, eg. to verify that EstimatePrice's assumptions hold for
+// a product
+func (c *Client) CheckQuote(ctx context.Context, product *ProductInfo, request *QuoteRequest) (*QuoteCheck, error) {
+	estimate, err := EstimatePrice(product, request)
+	if err != nil {
+		return nil, err
+	}
+	quote := *request
+	quote.ProductCode = product.ProductCode
+	res, err := c.Quote(ctx, &quote)
+	if err != nil {
+		return nil, err
+	}
+	return &QuoteCheck{
+		Estimate:   estimate,
+		Quote:      res,
+		Difference: roundPrice(res.Price - estimate.Price),
+	}, nil
+}
+
+// Requirement describes what a certificate has to support (see
+// Catalog.Rank). Zero values don't restrict the products. ValidityPeriod
+// is given in months, also for AlwaysOnSSL.
+type Requirement struct {
+	SubjectAltNameCount int
+	ValidityPeriod      int
+	ServerCount         int
+	// ValidationLevel is the minimum validation: "DV", "OV" or "EV"
+	ValidationLevel string
+	Wildcard        bool // wildcard common name
+	WildcardSAN     bool
+	IDN             bool
+	CA              string
+	Currency        string
+	// Filter optionally rejects further products
+	Filter func(*ProductInfo) bool
+}
+
+var validationRanks = map[string]int{"DV": 1, "OV": 2, "EV": 3}
+
+func (r *Requirement) satisfiedBy(p *ProductInfo) bool {
+	flags := p.Flags()
+	if flags.SMIME || flags.CodeSign {
+		return false
+	}
+	if r.ValidationLevel != "" && validationRanks[p.ValidationLevel()] < validationRanks[strings.ToUpper(r.ValidationLevel)] {
+		return false
+	}
+	if (r.Wildcard && !flags.Wildcard) || (r.WildcardSAN && !flags.WildcardSAN) || (r.IDN && !flags.IDN) {
+		return false
+	}
+	if r.CA != "" && !strings.EqualFold(r.CA, p.CA) {
+		return false
+	}
+	if r.Currency != "" && !strings.EqualFold(r.Currency, p.Currency) {
+		return false
+	}
+	return r.Filter == nil || r.Filter(p)
+}
+
+// Rank returns estimates for all SSL products satisfying requirement,
+// cheapest first. Products whose limits are exceeded (eg. by the number
+// of SANs) are left out. Prices in different currencies are compared
+// as they are, so set Requirement.Currency if the catalog mixes them.
+func (c *Catalog) Rank(ctx context.Context, requirement Requirement) ([]Estimate, error) {
+	products, err := c.Products(ctx, requirement.satisfiedBy)
+	if err != nil {
+		return nil, err
+	}
+	request := QuoteRequest{
+		SubjectAltNameCount: requirement.SubjectAltNameCount,
+		ValidityPeriod:      requirement.ValidityPeriod,
+		ServerCount:         requirement.ServerCount,
+	}
+	var estimates []Estimate
+	for i := range products {
+		request := request
+		if products[i].Flags().AlwaysOnSSL {
+			// AlwaysOnSSL is valid for up to a year, given in days
+			if request.ValidityPeriod > 12 {
+				continue
+			}
+			request.ValidityPeriod = 0
+		}
+		if e, err := EstimatePrice(&products[i], &request); err == nil {
+			estimates = append(estimates, *e)
+		}
+	}
+	sort.SliceStable(estimates, func(i, j int) bool {
+		return estimates[i].Price < estimates[j].Price
+	})
+	return estimates, nil
+}
@@ -0,0 +1,122 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestEstimatePriceMatchesQuote(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	for _, product := range certcentertest.DefaultProducts {
+		product := product
+		for _, request := range []certcenter.QuoteRequest{
+			{ValidityPeriod: 12},
+			{ValidityPeriod: 24},
+			{ValidityPeriod: 24, ServerCount: 3},
+			{ValidityPeriod: 12, SubjectAltNameCount: 2},
+			{ValidityPeriod: 24, SubjectAltNameCount: 4},
+			{ValidityPeriod: 36},
+			{},
+		} {
+			request := request
+			if product.Flags().AlwaysOnSSL && request.ValidityPeriod > 0 {
+				continue
+			}
+			check, err := c.CheckQuote(ctx, &product, &request)
+			if errors.Is(err, certcenter.ErrInvalidRequest) {
+				// beyond the product's limits
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s %+v: %v", product.ProductCode, request, err)
+			}
+			if !check.Matches(0.005) {
+				t.Errorf("%s %+v: estimated %.2f, quoted %.2f", product.ProductCode, request, check.Estimate.Price, check.Quote.Price)
+			}
+		}
+	}
+}
+
+func TestCheckQuoteMismatch(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	// a discount for two years, which EstimatePrice doesn't know about
+	srv.SetPrices("Comodo.PositiveSSL", certcentertest.PriceList{
+		12: {Base: 19, Server: 19},
+		24: {Base: 30, Server: 30},
+	})
+	product := certcentertest.DefaultProducts[0]
+
+	check, err := srv.Client().CheckQuote(context.Background(), &product, &certcenter.QuoteRequest{ValidityPeriod: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Estimate.Price != 38 || check.Quote.Price != 30 || check.Difference != -8 {
+		t.Errorf("got estimate %.2f, quote %.2f, difference %.2f; want 38, 30, -8",
+			check.Estimate.Price, check.Quote.Price, check.Difference)
+	}
+	if check.Matches(1) {
+		t.Error("a difference of 8 should not match")
+	}
+}
+
+func TestEstimatePriceLimits(t *testing.T) {
+	product := certcentertest.DefaultProducts[0] // PositiveSSL, no SANs
+	for _, request := range []certcenter.QuoteRequest{
+		{ValidityPeriod: 36},
+		{ValidityPeriod: -12},
+		{SubjectAltNameCount: 1},
+		{ServerCount: -1},
+	} {
+		if _, err := certcenter.EstimatePrice(&product, &request); !errors.Is(err, certcenter.ErrInvalidRequest) {
+			t.Errorf("%+v: got %v, want ErrInvalidRequest", request, err)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	catalog := srv.Client().NewCatalog()
+
+	estimates, err := catalog.Rank(context.Background(), certcenter.Requirement{
+		SubjectAltNameCount: 2,
+		ValidityPeriod:      12,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for i, e := range estimates {
+		codes = append(codes, e.ProductCode)
+		if i > 0 && e.Price < estimates[i-1].Price {
+			t.Errorf("%s (%.2f) ranked after %s (%.2f)", e.ProductCode, e.Price, estimates[i-1].ProductCode, estimates[i-1].Price)
+		}
+	}
+	want := []string{"Comodo.PositiveSSLMDC", "GeoTrust.QuickSSLPremium", "DigiCert.SecureSite", "DigiCert.SecureSiteEV"}
+	if len(codes) != len(want) {
+		t.Fatalf("got %v, want %v", codes, want)
+	}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("got %v, want %v", codes, want)
+		}
+	}
+
+	// OV or better, no SANs needed
+	estimates, err = catalog.Rank(context.Background(), certcenter.Requirement{ValidationLevel: "OV"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimates) != 2 || estimates[0].ProductCode != "DigiCert.SecureSite" {
+		t.Errorf("got %+v, want SecureSite and SecureSiteEV", estimates)
+	}
+}
@@ -0,0 +1,136 @@
+package certcenter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit limits the client to rps requests per second on average,
+// allowing bursts of up to burst requests. All operations (and retries)
+// of the client share this budget.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter.rate = rps
+		c.limiter.burst = burst
+	}
+}
+
+// WithMaxInFlight limits the number of concurrent requests of the client
+func WithMaxInFlight(n int) Option {
+	return func(c *Client) {
+		c.limiter.maxInFlight = n
+	}
+}
+
+// LimiterStats reports how much the client has been slowed down by
+// WithRateLimit and WithMaxInFlight
+type LimiterStats struct {
+	Requests  int64         // requests which passed the limiter
+	Delayed   int64         // requests which had to wait
+	TotalWait time.Duration // sum of all waiting times
+	MaxWait   time.Duration // longest waiting time of a single request
+	InFlight  int           // requests currently in flight
+}
+
+// LimiterStats returns a snapshot of the client's limiter metrics
+func (c *Client) LimiterStats() LimiterStats {
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	stats := c.limiter.stats
+	stats.InFlight = len(c.limiter.slots)
+	return stats
+}
+
+// limiter combines a token bucket with a semaphore
+type limiter struct {
+	rate        float64
+	burst       int
+	maxInFlight int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+	stats  LimiterStats
+}
+
+// init prepares the limiter once all options have been applied
+func (l *limiter) init() {
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	l.tokens = float64(l.burst)
+	if l.maxInFlight > 0 {
+		l.slots = make(chan struct{}, l.maxInFlight)
+	}
+}
+
+// acquire blocks until the request may be sent. The returned func
+// must be called once the request has been completed.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	started := time.Now()
+
+	if l.rate > 0 {
+		if !sleep(ctx, l.reserve(started)) {
+			l.cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			if l.rate > 0 {
+				l.cancel()
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	l.record(time.Since(started))
+	return release, nil
+}
+
+// reserve takes a token from the bucket and returns how long
+// to wait until it is actually available
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token which has not been used
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+func (l *limiter) record(wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Requests++
+	if wait < time.Millisecond {
+		return
+	}
+	l.stats.Delayed++
+	l.stats.TotalWait += wait
+	if wait > l.stats.MaxWait {
+		l.stats.MaxWait = wait
+	}
+}
@@ -0,0 +1,128 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestRateLimit(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithRateLimit(20, 2))
+	ctx := context.Background()
+
+	// two requests pass right away, the others wait 50ms each
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := c.Limit(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 150ms", elapsed)
+	}
+	stats := c.LimiterStats()
+	if stats.Requests != 5 || stats.Delayed != 3 || stats.TotalWait < 140*time.Millisecond ||
+		stats.MaxWait < 40*time.Millisecond || stats.InFlight != 0 {
+		t.Errorf("got %+v", stats)
+	}
+
+	// giving up while waiting for the limiter sends nothing
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err := c.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if n := srv.Calls("Limit"); n != 5 {
+		t.Errorf("Limit reached the server %d times, want 5", n)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+	c := certcenter.NewClient(certcenter.WithBaseURL(ts.URL), certcenter.WithBearer("token"), certcenter.WithMaxInFlight(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Limit(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Errorf("%d requests in flight at once, want 2", maxInFlight)
+	}
+	if stats := c.LimiterStats(); stats.Requests != 8 || stats.Delayed == 0 || stats.InFlight != 0 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestCancelledRequestReturnsToken(t *testing.T) {
+	block := make(chan struct{})
+	var once sync.Once
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { <-block })
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer ts.Close()
+	// two tokens, and the next one takes two seconds
+	c := certcenter.NewClient(certcenter.WithBaseURL(ts.URL), certcenter.WithBearer("token"),
+		certcenter.WithRateLimit(0.5, 2), certcenter.WithMaxInFlight(1))
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Limit(ctx)
+		done <- err
+	}()
+	for c.LimiterStats().InFlight == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// gets a token, but gives up waiting for the slot
+	cancelled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Limit(cancelled); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the token of the cancelled request is still available
+	start := time.Now()
+	if _, err := c.Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("waited %v, want the cancelled request's token reused", elapsed)
+	}
+}
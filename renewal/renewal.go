@@ -0,0 +1,497 @@
+// Package renewal renews certificates before they expire. A Manager
+// finds the orders whose Fulfillment.EndDate falls within its window,
+// either by querying GetOrders or by looking into a store, and places
+// a renewal order for each with a freshly generated key and CSR.
+// With a store, the new key is saved as soon as the order has been
+// placed, and renewals left pending are finished by the next Run.
+//
+//	m := renewal.NewManager(client)
+//	m.Store = s // optional, see package store
+//	results, err := m.Run(ctx)
+//	..
+//	for _, r := range results {
+//		log.Println(r.Original.CommonName, r.Outcome, r.Err)
+//	}
+package renewal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/csr"
+	"certcenter.com/go/store"
+)
+
+// Outcome tells how the renewal of an order ended
+type Outcome int
+
+const (
+	// Renewed means the renewal has been issued
+	Renewed Outcome = iota + 1
+	// Pending means the renewal has been ordered, but was not complete
+	// before WaitTimeout (eg. domain control validation is missing) or
+	// the wait failed (see Result.Err). See Manager.Resume.
+	Pending
+	// Failed means the renewal could not be ordered or has been
+	// cancelled. See Result.Err.
+	Failed
+)
+
+var outcomeNames = map[Outcome]string{
+	Renewed: "renewed",
+	Pending: "pending",
+	Failed:  "failed",
+}
+
+func (o Outcome) String() string {
+	if name, ok := outcomeNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Result reports the renewal of a single order
+type Result struct {
+	// Original is the order which has been renewed. Results of Resume
+	// only carry its CertCenterOrderID and CommonName.
+	Original certcenter.OrderInfo
+	Outcome  Outcome
+	// CertCenterOrderID identifies the renewal order, unless it could
+	// not be placed
+	CertCenterOrderID int64
+	// Key is the private key of the renewal. If the Manager has a
+	// Store, it has been saved there as soon as the order was placed.
+	// Otherwise it's up to you to keep it, especially while Pending.
+	Key crypto.Signer
+	// Order is the completed renewal (Renewed only)
+	Order *certcenter.OrderInfo
+	Err   error
+}
+
+// Manager renews expiring certificates. Create it with NewManager and
+// adjust the exported fields before calling Scan or Run.
+type Manager struct {
+	client *certcenter.Client
+
+	// Window is how long before Fulfillment.EndDate certificates are
+	// renewed
+	Window time.Duration
+	// Store, if set, is scanned instead of GetOrders. The keys of
+	// renewals are saved to it as pending entries once they have been
+	// ordered, and completed with the certificate.
+	Store store.Store
+	// KeyType of the new keys. By default, the type of the expiring
+	// certificate's key is kept.
+	KeyType csr.KeyType
+	// Filter optionally excludes orders from renewal
+	Filter func(*certcenter.OrderInfo) bool
+	// PartnerOrderID returns the PartnerOrderID of a renewal order
+	// (default: "renewal-<CertCenterOrderID>")
+	PartnerOrderID func(original *certcenter.OrderInfo) string
+	// Wait configures polling for completion. WaitTimeout limits the
+	// wait for each order; renewals not complete by then are Pending.
+	Wait        certcenter.WaitOptions
+	WaitTimeout time.Duration
+	// Concurrency is the number of renewals processed at once
+	Concurrency int
+	// OnResult is called as soon as an order has been processed
+	OnResult func(Result)
+}
+
+// NewManager returns a Manager renewing certificates 30 days before
+// they expire and waiting up to an hour for each renewal
+func NewManager(client *certcenter.Client) *Manager {
+	return &Manager{
+		client:      client,
+		Window:      30 * 24 * time.Hour,
+		WaitTimeout: time.Hour,
+		Concurrency: 4,
+	}
+}
+
+// Scan returns the completed orders expiring within Window which have
+// not been renewed yet. Orders superseded by a newer order for the
+// same CommonName (pending or complete) are left out.
+func (m *Manager) Scan(ctx context.Context) ([]certcenter.OrderInfo, error) {
+	var expiring []certcenter.OrderInfo
+	var err error
+	if m.Store != nil {
+		expiring, err = m.scanStore(ctx)
+	} else {
+		expiring, err = m.scanOrders(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []certcenter.OrderInfo
+	for i := range expiring {
+		order := &expiring[i]
+		if m.Filter != nil && !m.Filter(order) {
+			continue
+		}
+		superseded, err := m.superseded(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+		if !superseded {
+			candidates = append(candidates, *order)
+		}
+	}
+	return candidates, nil
+}
+
+// include makes GetOrder(s) return what's needed to renew an order
+var include = certcenter.GetOrderRequest{
+	IncludeFulfillment:       true,
+	IncludeOrderParameters:   true,
+	IncludeContacts:          true,
+	IncludeOrganizationInfos: true,
+}
+
+func endDate(order *certcenter.OrderInfo) time.Time {
+	if !order.Fulfillment.EndDate.IsZero() {
+		return order.Fulfillment.EndDate
+	}
+	return order.OrderStatus.EndDate
+}
+
+func (m *Manager) expiring(end time.Time, now time.Time) bool {
+	return end.After(now) && end.Before(now.Add(m.Window))
+}
+
+func (m *Manager) scanOrders(ctx context.Context) ([]certcenter.OrderInfo, error) {
+	now := time.Now()
+	it := m.client.IterateOrders(ctx, &certcenter.GetOrdersRequest{
+		Status:                   []certcenter.MajorStatus{certcenter.StatusComplete},
+		ExpirationDateFrom:       now,
+		ExpirationDateTo:         now.Add(m.Window),
+		OrderBy:                  certcenter.OrderByExpirationDate,
+		OrderDir:                 certcenter.OrderAscending,
+		IncludeFulfillment:       include.IncludeFulfillment,
+		IncludeOrderParameters:   include.IncludeOrderParameters,
+		IncludeContacts:          include.IncludeContacts,
+		IncludeOrganizationInfos: include.IncludeOrganizationInfos,
+	})
+	defer it.Close()
+	var orders []certcenter.OrderInfo
+	for it.Next() {
+		// the API filters by OrderStatus.EndDate, which may differ
+		if order := it.Order(); m.expiring(endDate(&order), now) {
+			orders = append(orders, order)
+		}
+	}
+	return orders, it.Err()
+}
+
+func (m *Manager) scanStore(ctx context.Context) ([]certcenter.OrderInfo, error) {
+	now := time.Now()
+	list, err := m.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var orders []certcenter.OrderInfo
+	for _, meta := range list {
+		if !m.expiring(meta.EndDate, now) {
+			continue
+		}
+		request := include
+		request.CertCenterOrderID = meta.CertCenterOrderID
+		res, err := m.client.GetOrder(ctx, &request)
+		if err != nil {
+			return nil, err
+		}
+		if res.OrderInfo.OrderStatus.MajorStatus == certcenter.StatusComplete {
+			orders = append(orders, res.OrderInfo)
+		}
+	}
+	return orders, nil
+}
+
+// superseded reports whether another order for the same CommonName has
+// been placed after order (eg. a renewal in a previous run)
+func (m *Manager) superseded(ctx context.Context, order *certcenter.OrderInfo) (bool, error) {
+	if order.CommonName == "" {
+		return false, nil
+	}
+	it := m.client.IterateOrders(ctx, &certcenter.GetOrdersRequest{
+		Status:        []certcenter.MajorStatus{certcenter.StatusPending, certcenter.StatusComplete},
+		CommonName:    order.CommonName,
+		OrderDateFrom: order.OrderStatus.OrderDate,
+	})
+	defer it.Close()
+	for it.Next() {
+		other := it.Order()
+		if other.CertCenterOrderID != order.CertCenterOrderID &&
+			strings.EqualFold(other.CommonName, order.CommonName) &&
+			!other.OrderStatus.OrderDate.Before(order.OrderStatus.OrderDate) {
+			return true, nil
+		}
+	}
+	return false, it.Err()
+}
+
+// Run finishes pending renewals (see Resume), then scans for expiring
+// certificates and renews them. It returns an error only if the scan
+// failed; the outcome of each renewal is part of the results.
+func (m *Manager) Run(ctx context.Context) ([]Result, error) {
+	resumed, err := m.Resume(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := m.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(candidates))
+	concurrency := m.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = m.Renew(ctx, &candidates[i])
+		}(i)
+	}
+	wg.Wait()
+	return append(resumed, results...), nil
+}
+
+// Resume looks after the renewals left pending in the Store by earlier
+// runs. Completed ones are fetched into the Store along with the key
+// saved there, cancelled ones are removed. Without a Store, there is
+// nothing to resume.
+func (m *Manager) Resume(ctx context.Context) ([]Result, error) {
+	if m.Store == nil {
+		return nil, nil
+	}
+	list, err := m.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, meta := range list {
+		if !meta.Pending {
+			continue
+		}
+		result := m.resume(ctx, meta)
+		if m.OnResult != nil {
+			m.OnResult(result)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (m *Manager) resume(ctx context.Context, meta store.Metadata) Result {
+	result := Result{Outcome: Pending, CertCenterOrderID: meta.CertCenterOrderID}
+	result.Original.CertCenterOrderID = meta.Renews
+	result.Original.CommonName = meta.CommonName
+
+	entry, err := m.Store.Get(ctx, meta.CertCenterOrderID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	key, err := csr.ParseKey(entry.Key)
+	if err != nil {
+		result.Err = fmt.Errorf("renewal: key of order %d: %w", meta.CertCenterOrderID, err)
+		return result
+	}
+	result.Key = key
+
+	request := include
+	request.CertCenterOrderID = meta.CertCenterOrderID
+	res, err := m.client.GetOrder(ctx, &request)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	switch status := res.OrderInfo.OrderStatus.MajorStatus; status {
+	case certcenter.StatusPending:
+		return result
+	case certcenter.StatusComplete:
+		if _, err := store.Fetch(ctx, m.client, m.Store, meta.CertCenterOrderID, key); err != nil {
+			result.Err = fmt.Errorf("renewal: order %d completed, but could not be stored: %w", meta.CertCenterOrderID, err)
+			return result
+		}
+		result.Outcome = Renewed
+		result.Order = &res.OrderInfo
+	default:
+		result.Outcome = Failed
+		result.Err = fmt.Errorf("%w (%s)", certcenter.ErrOrderNotFulfilled, status)
+		if err := m.Store.Delete(ctx, meta.CertCenterOrderID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			result.Err = err
+		}
+	}
+	return result
+}
+
+// Renew orders a renewal of original, reusing its OrderParameters,
+// organization and contacts with a new key and CSR, and waits for it
+// to complete. original must include all of them (see Scan).
+func (m *Manager) Renew(ctx context.Context, original *certcenter.OrderInfo) Result {
+	result := m.renew(ctx, original)
+	if m.OnResult != nil {
+		m.OnResult(result)
+	}
+	return result
+}
+
+func (m *Manager) renew(ctx context.Context, original *certcenter.OrderInfo) Result {
+	result := Result{Original: *original, Outcome: Failed}
+
+	keyType := m.KeyType
+	if keyType == "" {
+		keyType = keyTypeOf(original.Fulfillment.Certificate)
+	}
+	request := &csr.Request{
+		CommonName:      original.CommonName,
+		SubjectAltNames: original.OrderParameters.SubjectAltNames,
+	}
+	if original.OrganizationInfo.OrganizationName != "" {
+		request.SetOrganization(&original.OrganizationInfo)
+	}
+	pemCSR, key, err := csr.Generate(request, keyType)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Key = key
+
+	params := original.OrderParameters
+	params.CSR = pemCSR
+	params.IsRenewal = true
+	params.IsCompetitiveUpgrade = false
+	params.PartnerOrderID = fmt.Sprintf("renewal-%d", original.CertCenterOrderID)
+	if m.PartnerOrderID != nil {
+		params.PartnerOrderID = m.PartnerOrderID(original)
+	}
+	order := &certcenter.OrderRequest{OrderParameters: &params}
+	if original.OrganizationInfo.OrganizationName != "" {
+		order.OrganizationInfo = &original.OrganizationInfo
+	}
+	if contact := original.ContactInfo.AdminContact; contact.Email != "" {
+		order.AdminContact = &contact
+	}
+	if contact := original.ContactInfo.TechContact; contact.Email != "" {
+		order.TechContact = &contact
+	}
+	res, err := m.client.Order(ctx, order)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.CertCenterOrderID = res.CertCenterOrderID
+	var saveErr error
+	if m.Store != nil {
+		saveErr = m.savePending(ctx, original, res.CertCenterOrderID, &params, key)
+	}
+
+	waitCtx := ctx
+	if m.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, m.WaitTimeout)
+		defer cancel()
+	}
+	renewed, err := m.client.WaitForOrder(waitCtx, res.CertCenterOrderID, &m.Wait)
+	switch {
+	case errors.Is(err, certcenter.ErrOrderNotFulfilled):
+		// cancelled or revoked, the key is of no use
+		result.Err = err
+		if m.Store != nil && saveErr == nil {
+			if err := m.Store.Delete(ctx, res.CertCenterOrderID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				result.Err = err
+			}
+		}
+		return result
+	case err != nil:
+		// the order may still be completed, Resume picks it up
+		result.Outcome = Pending
+		if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			result.Err = err
+		} else if saveErr != nil {
+			result.Err = saveErr
+		}
+		return result
+	}
+	result.Order = renewed
+
+	if m.Store != nil {
+		if _, err := store.Fetch(ctx, m.client, m.Store, res.CertCenterOrderID, key); err != nil {
+			result.Err = fmt.Errorf("renewal: order %d completed, but could not be stored: %w", res.CertCenterOrderID, err)
+			return result
+		}
+	}
+	result.Outcome = Renewed
+	return result
+}
+
+// savePending saves key as a pending entry of the renewal order id, so
+// it isn't lost if the order isn't complete before WaitTimeout
+func (m *Manager) savePending(ctx context.Context, original *certcenter.OrderInfo, id int64, params *certcenter.OrderParameters, key crypto.Signer) error {
+	data, err := csr.EncodeKey(key)
+	if err != nil {
+		return err
+	}
+	err = m.Store.Put(ctx, &store.Entry{
+		Metadata: store.Metadata{
+			CertCenterOrderID: id,
+			CommonName:        original.CommonName,
+			SubjectAltNames:   params.SubjectAltNames,
+			ProductCode:       params.ProductCode,
+			PartnerOrderID:    params.PartnerOrderID,
+			Renews:            original.CertCenterOrderID,
+			Pending:           true,
+		},
+		Key: data,
+	})
+	if err != nil {
+		return fmt.Errorf("renewal: order %d placed, but its key could not be stored: %w", id, err)
+	}
+	return nil
+}
+
+// keyTypeOf returns the KeyType matching the key of a PEM-encoded
+// certificate, or RSA2048 if it's unknown
+func keyTypeOf(certificate string) csr.KeyType {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return csr.RSA2048
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return csr.RSA2048
+	}
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		switch bits := k.N.BitLen(); {
+		case bits > 3072:
+			return csr.RSA4096
+		case bits > 2048:
+			return csr.RSA3072
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize > 256 {
+			return csr.ECDSAP384
+		}
+		return csr.ECDSAP256
+	}
+	return csr.RSA2048
+}
@@ -0,0 +1,220 @@
+package renewal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/bundle"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+	"certcenter.com/go/renewal"
+	"certcenter.com/go/store"
+)
+
+// issuedOrder places an order for commonName which has been completed
+// monthsAgo, and stores it in s (if given). The certificate is valid
+// for 12 months.
+func issuedOrder(t *testing.T, srv *certcentertest.Server, s store.Store, commonName string, monthsAgo int) int64 {
+	t.Helper()
+	ctx := context.Background()
+	pemCSR, key, err := csr.Generate(&csr.Request{CommonName: commonName}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := srv.Client()
+	res, err := c.Order(ctx, &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 12,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Now = func() time.Time { return time.Now().AddDate(0, -monthsAgo, 0) }
+	defer func() { srv.Now = time.Now }()
+	if err := srv.CompleteOrder(res.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	if s != nil {
+		if _, err := store.Fetch(ctx, c, s, res.CertCenterOrderID, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.CertCenterOrderID
+}
+
+func newManager(srv *certcentertest.Server, s store.Store) *renewal.Manager {
+	m := renewal.NewManager(srv.Client())
+	m.Window = 60 * 24 * time.Hour
+	m.Store = s
+	m.Wait = certcenter.WaitOptions{MinInterval: 10 * time.Millisecond}
+	m.WaitTimeout = 100 * time.Millisecond
+	return m
+}
+
+func TestScan(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	expiring := issuedOrder(t, srv, nil, "www.example.com", 11)
+	issuedOrder(t, srv, nil, "shop.example.com", 6)  // not expiring yet
+	issuedOrder(t, srv, nil, "mail.example.com", 11) // filtered
+	m := newManager(srv, nil)
+	m.Filter = func(o *certcenter.OrderInfo) bool { return o.CommonName != "mail.example.com" }
+
+	candidates, err := m.Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].CertCenterOrderID != expiring {
+		t.Fatalf("got %d candidates, want order %d", len(candidates), expiring)
+	}
+	if candidates[0].OrderParameters.ProductCode != "Comodo.PositiveSSL" {
+		t.Errorf("got %+v, want the OrderParameters included", candidates[0].OrderParameters)
+	}
+}
+
+func TestRunPendingThenResume(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	s := new(store.MemoryStore)
+	original := issuedOrder(t, srv, s, "www.example.com", 11)
+	m := newManager(srv, s)
+
+	results, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Outcome != renewal.Pending || results[0].Err != nil {
+		t.Fatalf("got %+v, want a single pending renewal", results)
+	}
+	r := results[0]
+	if r.Key == nil || r.CertCenterOrderID == 0 {
+		t.Fatalf("got %+v, want the renewal's order and key", r)
+	}
+
+	// the key has been saved as soon as the order was placed
+	entry, err := s.Get(ctx, r.CertCenterOrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Pending || entry.Renews != original || entry.Key == "" {
+		t.Fatalf("got %+v, want a pending entry with the key", entry.Metadata)
+	}
+	if _, err := store.Latest(ctx, s, "www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a later run completes the renewal instead of ordering another one
+	if err := srv.CompleteOrder(r.CertCenterOrderID); err != nil {
+		t.Fatal(err)
+	}
+	results, err = newManager(srv, s).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Outcome != renewal.Renewed || results[0].CertCenterOrderID != r.CertCenterOrderID {
+		t.Fatalf("got %+v, want the pending renewal completed", results)
+	}
+	if results[0].Original.CertCenterOrderID != original {
+		t.Errorf("got original %d, want %d", results[0].Original.CertCenterOrderID, original)
+	}
+	if n := srv.Calls("Order"); n != 2 {
+		t.Errorf("%d orders placed, want 2", n)
+	}
+
+	latest, err := store.Latest(ctx, s, "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.CertCenterOrderID != r.CertCenterOrderID || latest.Pending || latest.Renews != original {
+		t.Fatalf("got %+v, want the completed renewal", latest.Metadata)
+	}
+	b, err := latest.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bundle.MatchesKey(b.Leaf, r.Key) {
+		t.Error("stored certificate doesn't match the key of the first run")
+	}
+}
+
+func TestResumeCancelled(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	s := new(store.MemoryStore)
+	issuedOrder(t, srv, s, "www.example.com", 11)
+
+	results, err := newManager(srv, s).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Outcome != renewal.Pending {
+		t.Fatalf("got %+v, want a pending renewal", results)
+	}
+	id := results[0].CertCenterOrderID
+	if err := srv.SetOrderStatus(id, certcenter.StatusCancelled, "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = newManager(srv, s).Resume(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Outcome != renewal.Failed || !errors.Is(results[0].Err, certcenter.ErrOrderNotFulfilled) {
+		t.Fatalf("got %+v, want the cancelled renewal failed", results)
+	}
+	if _, err := s.Get(ctx, id); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("got %v, want the pending entry removed", err)
+	}
+}
+
+func TestRenewImmediately(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	s := new(store.MemoryStore)
+	original := issuedOrder(t, srv, s, "www.example.com", 11)
+	m := newManager(srv, s)
+	m.PartnerOrderID = func(o *certcenter.OrderInfo) string { return "renew-www" }
+
+	var reported []renewal.Result
+	m.OnResult = func(r renewal.Result) { reported = append(reported, r) }
+	// complete the renewal while it's being waited for
+	m.Wait.OnUpdate = func(u certcenter.OrderUpdate) {
+		if !u.Done {
+			srv.CompleteOrder(u.CertCenterOrderID)
+		}
+	}
+	m.WaitTimeout = 5 * time.Second
+
+	results, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Outcome != renewal.Renewed || results[0].Err != nil {
+		t.Fatalf("got %+v, want a completed renewal", results)
+	}
+	if len(reported) != 1 {
+		t.Errorf("OnResult called %d times, want 1", len(reported))
+	}
+	order, _ := srv.Order(results[0].CertCenterOrderID)
+	if !order.OrderParameters.IsRenewal || order.OrderParameters.PartnerOrderID != "renew-www" {
+		t.Errorf("got %+v, want a renewal with the custom PartnerOrderID", order.OrderParameters)
+	}
+	entry, err := s.Get(ctx, results[0].CertCenterOrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Pending || entry.Certificate == "" || entry.Key == "" || entry.Renews != original {
+		t.Errorf("got %+v, want a complete entry", entry.Metadata)
+	}
+}
@@ -0,0 +1,222 @@
+package certcenter
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls if and how failed requests are retried. Network
+// errors and responses with status 429, 500, 502, 503 or 504 are retried,
+// but only for idempotent verbs (GET, PUT, DELETE) unless RetryOrders is
+// set. POST requests rejected with 429 are always retried, as the API
+// did not process them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the
+	// first one. Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles with
+	// every further attempt (with jitter) up to MaxBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays
+	// requested by the API via Retry-After
+	MaxBackoff time.Duration
+	// RetryOrders allows retrying POST /Order requests which carry a
+	// PartnerOrderID. Before each retry, and after the last attempt
+	// failed, the client looks for an order with that PartnerOrderID
+	// (see GetModifiedOrders) and returns it instead of ordering twice.
+	RetryOrders bool
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for batch jobs
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// WithRetryPolicy enables retries according to policy (see DefaultRetryPolicy)
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// orderLookbehind is subtracted from the time of the first attempt
+// when looking for orders which have been placed by a failed attempt
+const orderLookbehind = 5 * time.Minute
+
+// retry calls attempt until it succeeds or the retry policy of
+// the client gives up
+func (c *Client) retry(ctx context.Context, req *apiRequest, attempt func() error) error {
+	policy := c.retryPolicy
+	started := time.Now()
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || !retryable(ctx, err) {
+			return err
+		}
+		// a rejected request (429) has not been processed, so even
+		// POST requests can be repeated
+		rejected := tooManyRequests(err)
+		partnerOrderID, guarded := req.orderGuard()
+		guarded = guarded && !rejected
+		last := n >= policy.MaxAttempts
+		if (last || req.httpMethod == "POST" && !rejected) && !guarded {
+			return err
+		}
+
+		if !sleep(ctx, policy.backoff(n, req.retryAfter)) {
+			return err
+		}
+
+		// the outcome of a failed order is unknown, even after the
+		// last attempt: it may have been placed (and charged)
+		if guarded {
+			found, lookupErr := c.findPlacedOrder(ctx, req, partnerOrderID, started)
+			if lookupErr != nil {
+				return err
+			}
+			if found {
+				return nil
+			}
+		}
+		if last {
+			return err
+		}
+	}
+}
+
+// tooManyRequests reports whether err is a 429 response
+func tooManyRequests(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// retryable reports whether err is worth another attempt
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// backoff returns the delay before attempt n+1
+func (p RetryPolicy) backoff(n int, retryAfter time.Duration) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < n && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d > 0 {
+		// equal jitter: somewhere between d/2 and d
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if retryAfter > d {
+		d = retryAfter
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			d = p.MaxBackoff
+		}
+	}
+	return d
+}
+
+// parseRetryAfter interprets a Retry-After header (seconds or HTTP-date)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done. It reports whether d elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// orderGuard reports whether req is an order which may be retried
+// safely, and the PartnerOrderID to identify it by
+func (req *apiRequest) orderGuard() (string, bool) {
+	if req.method != "Order" || !req.client.retryPolicy.RetryOrders {
+		return "", false
+	}
+	order, ok := req.request.(*OrderRequest)
+	if !ok || order.OrderParameters == nil || order.OrderParameters.PartnerOrderID == "" {
+		return "", false
+	}
+	return order.OrderParameters.PartnerOrderID, true
+}
+
+// findPlacedOrder looks for an order with the given PartnerOrderID
+// which has been placed since the first attempt of req. If one is
+// found, req.result is filled in as if the attempt had succeeded.
+func (c *Client) findPlacedOrder(ctx context.Context, req *apiRequest, partnerOrderID string, since time.Time) (bool, error) {
+	info, err := c.placedOrder(ctx, partnerOrderID, since)
+	if err != nil || info == nil {
+		return false, err
+	}
+	result := req.result.(*OrderResult)
+	result.Success = true
+	result.CertCenterOrderID = info.CertCenterOrderID
+	result.Timestamp = info.OrderStatus.OrderDate
+	result.OrderParameters.PartnerOrderID = info.OrderParameters.PartnerOrderID
+	result.OrderParameters.ProductCode = info.OrderParameters.ProductCode
+	result.OrderParameters.ValidityPeriod = info.OrderParameters.ValidityPeriod
+	result.OrderParameters.SubjectAltNames = info.OrderParameters.SubjectAltNames
+	return true, nil
+}
+
+// placedOrder returns the order with the given PartnerOrderID which
+// has been placed since the given time, or nil if there is none
+func (c *Client) placedOrder(ctx context.Context, partnerOrderID string, since time.Time) (*OrderInfo, error) {
+	res, err := c.GetModifiedOrders(ctx, &GetModifiedOrdersRequest{
+		FromDate:               since.Add(-orderLookbehind),
+		ToDate:                 time.Now().Add(orderLookbehind),
+		IncludeOrderParameters: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.OrderInfos {
+		if res.OrderInfos[i].OrderParameters.PartnerOrderID == partnerOrderID {
+			return &res.OrderInfos[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,170 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+var fastRetries = certcenter.RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  5 * time.Millisecond,
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithRetryPolicy(fastRetries))
+
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusServiceUnavailable, Times: 2})
+	if _, err := c.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Limit"); n != 3 {
+		t.Errorf("got %d calls, want 3", n)
+	}
+
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusBadGateway, Times: 5})
+	_, err := c.Limit(context.Background())
+	var apiErr *certcenter.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got %v, want a 502 APIError", err)
+	}
+	if n := srv.Calls("Limit"); n != 6 {
+		t.Errorf("got %d calls, want 6", n)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithRetryPolicy(fastRetries))
+
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusBadRequest})
+	if _, err := c.Limit(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := srv.Calls("Limit"); n != 1 {
+		t.Errorf("got %d calls, want 1", n)
+	}
+}
+
+func TestRetryPostUnguarded(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithRetryPolicy(fastRetries))
+	ctx := context.Background()
+
+	// the outcome of a failed order is unknown, it must not be repeated
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusBadGateway})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "")); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := srv.Calls("Order"); n != 1 {
+		t.Errorf("got %d calls, want 1", n)
+	}
+
+	// 429 means the order has not been processed
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusTooManyRequests})
+	if _, err := c.Order(ctx, newOrder(t, "www.example.com", "")); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Order"); n != 3 {
+		t.Errorf("got %d calls, want 3", n)
+	}
+}
+
+func TestRetryOrders(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	policy := fastRetries
+	policy.RetryOrders = true
+	c := srv.Client(certcenter.WithRetryPolicy(policy))
+	ctx := context.Background()
+
+	// the first response is lost: the retry must find the order
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusBadGateway, Processed: true})
+	res, err := c.Order(ctx, newOrder(t, "www.example.com", "partner-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Order"); n != 1 {
+		t.Errorf("got %d calls, want 1", n)
+	}
+	if _, ok := srv.Order(res.CertCenterOrderID); !ok || res.OrderParameters.PartnerOrderID != "partner-1" {
+		t.Errorf("got %+v, want the placed order", res)
+	}
+}
+
+func TestRetryOrdersLastAttempt(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	policy := fastRetries
+	policy.MaxAttempts = 2
+	policy.RetryOrders = true
+	c := srv.Client(certcenter.WithRetryPolicy(policy))
+
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusBadGateway})
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusGatewayTimeout, Processed: true})
+	res, err := c.Order(context.Background(), newOrder(t, "www.example.com", "partner-2"))
+	if err != nil {
+		t.Fatalf("the order placed by the last attempt has not been found: %v", err)
+	}
+	if n := srv.Calls("Order"); n != 2 {
+		t.Errorf("got %d calls, want 2", n)
+	}
+	if res.CertCenterOrderID == 0 {
+		t.Error("CertCenterOrderID is missing")
+	}
+}
+
+func TestRetryOrdersRejected(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	policy := fastRetries
+	policy.RetryOrders = true
+	c := srv.Client(certcenter.WithRetryPolicy(policy))
+
+	srv.FailNext("Order", certcentertest.Failure{StatusCode: http.StatusTooManyRequests})
+	if _, err := c.Order(context.Background(), newOrder(t, "www.example.com", "partner-3")); err != nil {
+		t.Fatal(err)
+	}
+	// a rejected order needs no lookup
+	if n := srv.Calls("GetModifiedOrders"); n != 0 {
+		t.Errorf("got %d lookups, want 0", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	// Retry-After outweighs the backoff
+	policy := fastRetries
+	policy.MaxBackoff = 5 * time.Second
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second})
+	start := time.Now()
+	if _, err := srv.Client(certcenter.WithRetryPolicy(policy)).Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After to be respected", elapsed)
+	}
+
+	// but not MaxBackoff
+	srv.FailNext("Limit", certcentertest.Failure{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute})
+	start = time.Now()
+	if _, err := srv.Client(certcenter.WithRetryPolicy(fastRetries)).Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retried after %v, want at most MaxBackoff", elapsed)
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileStore keeps each entry in a JSON file named after its
+// CertCenterOrderID. As the files hold private keys, the directory and
+// the files are only accessible by the owner. Files are replaced
+// atomically, so readers never see partial entries.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore in dir, creating it if necessary
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id int64) string {
+	return filepath.Join(f.dir, strconv.FormatInt(id, 10)+".json")
+}
+
+// Get implements Store
+func (f *FileStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	data, err := os.ReadFile(f.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: order %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := new(Entry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("store: %s: %v", f.path(id), err)
+	}
+	return entry, nil
+}
+
+// Put implements Store
+func (f *FileStore) Put(ctx context.Context, entry *Entry) error {
+	entry.Updated = time.Now()
+	data, err := json.MarshalIndent(entry, "", "\t")
+	if err != nil {
+		return err
+	}
+	// CreateTemp creates files with mode 0600
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(entry.CertCenterOrderID))
+}
+
+// List implements Store
+func (f *FileStore) List(ctx context.Context) ([]Metadata, error) {
+	files, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var list []Metadata
+	for _, file := range files {
+		name := file.Name()
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		entry, err := f.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, entry.Metadata)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CertCenterOrderID < list[j].CertCenterOrderID
+	})
+	return list, nil
+}
+
+// Delete implements Store
+func (f *FileStore) Delete(ctx context.Context, id int64) error {
+	err := os.Remove(f.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: order %d", ErrNotFound, id)
+	}
+	return err
+}
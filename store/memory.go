@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps entries in memory, eg. for tests. The zero value
+// is ready to use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[int64]*Entry
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: order %d", ErrNotFound, id)
+	}
+	return entry.clone(), nil
+}
+
+// Put implements Store
+func (m *MemoryStore) Put(ctx context.Context, entry *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = make(map[int64]*Entry)
+	}
+	entry.Updated = time.Now()
+	m.entries[entry.CertCenterOrderID] = entry.clone()
+	return nil
+}
+
+// List implements Store
+func (m *MemoryStore) List(ctx context.Context) ([]Metadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Metadata, 0, len(m.entries))
+	for _, entry := range m.entries {
+		list = append(list, entry.clone().Metadata)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CertCenterOrderID < list[j].CertCenterOrderID
+	})
+	return list, nil
+}
+
+// Delete implements Store
+func (m *MemoryStore) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[id]; !ok {
+		return fmt.Errorf("%w: order %d", ErrNotFound, id)
+	}
+	delete(m.entries, id)
+	return nil
+}
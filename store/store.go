@@ -0,0 +1,203 @@
+// Package store persists fulfilled certificates along with their keys,
+// keyed by CertCenterOrderID.
+//
+//	s, err := store.NewFileStore("/var/lib/myapp/certs")
+//	..
+//	entry, err := store.Fetch(ctx, client, s, orderID, key)
+//	..
+//	latest, err := store.Latest(ctx, s, "www.example.com")
+//
+// Entries carry metadata (CommonName, ProductCode, EndDate, ..), so
+// certificates can be listed and looked up without parsing them.
+// Pending entries hold the key of an order until it is fulfilled.
+package store
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/bundle"
+	"certcenter.com/go/csr"
+)
+
+// ErrNotFound is reported for unknown CertCenterOrderIDs
+var ErrNotFound = errors.New("store: certificate not found")
+
+// Metadata describes a stored certificate
+type Metadata struct {
+	CertCenterOrderID int64
+	CommonName        string
+	SubjectAltNames   []string `json:",omitempty"`
+	ProductCode       string
+	PartnerOrderID    string `json:",omitempty"`
+	StartDate         time.Time
+	EndDate           time.Time
+	// Renews is the CertCenterOrderID of the order this one renews
+	Renews int64 `json:",omitempty"`
+	// Pending entries hold only the key of an order which has not been
+	// fulfilled yet (see package renewal). Fetch completes them.
+	Pending bool `json:",omitempty"`
+	// Updated is set by Put
+	Updated time.Time
+}
+
+// Entry is a stored certificate. All values are PEM-encoded.
+type Entry struct {
+	Metadata
+	Certificate string
+	// Chain holds the intermediates, starting with the leaf's issuer
+	Chain string `json:",omitempty"`
+	// Key is the PKCS#8 private key, if known
+	Key string `json:",omitempty"`
+}
+
+// Store is implemented by the backends. Get and Delete return
+// ErrNotFound for unknown orders. List returns the metadata of all
+// entries, ordered by CertCenterOrderID.
+type Store interface {
+	Get(ctx context.Context, id int64) (*Entry, error)
+	Put(ctx context.Context, entry *Entry) error
+	List(ctx context.Context) ([]Metadata, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// Bundle parses the entry for export (see package bundle)
+func (e *Entry) Bundle() (*bundle.Bundle, error) {
+	if e.Pending {
+		return nil, fmt.Errorf("store: order %d is pending", e.CertCenterOrderID)
+	}
+	b, err := bundle.Parse(e.Certificate, e.Chain)
+	if err != nil {
+		return nil, err
+	}
+	if e.Key != "" {
+		key, err := csr.ParseKey(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.SetKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Find returns the metadata of all certificates for commonName, the
+// one expiring last (or the newest order, if equal) first. Pending
+// entries are left out.
+func Find(ctx context.Context, s Store, commonName string) ([]Metadata, error) {
+	all, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []Metadata
+	for _, m := range all {
+		if !m.Pending && strings.EqualFold(m.CommonName, commonName) {
+			found = append(found, m)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].EndDate.Equal(found[j].EndDate) {
+			return found[i].CertCenterOrderID > found[j].CertCenterOrderID
+		}
+		return found[i].EndDate.After(found[j].EndDate)
+	})
+	return found, nil
+}
+
+// Latest returns the entry for commonName which expires last
+func Latest(ctx context.Context, s Store, commonName string) (*Entry, error) {
+	found, err := Find(ctx, s, commonName)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, commonName)
+	}
+	return s.Get(ctx, found[0].CertCenterOrderID)
+}
+
+// Fetch retrieves the fulfillment of a completed order with GetOrder
+// and stores it, completing a pending entry. key is the certificate's
+// private key. If it's nil, the key of an existing entry is kept as
+// long as it still matches.
+func Fetch(ctx context.Context, client *certcenter.Client, s Store, id int64, key crypto.Signer) (*Entry, error) {
+	res, err := client.GetOrder(ctx, &certcenter.GetOrderRequest{
+		CertCenterOrderID:      id,
+		IncludeFulfillment:     true,
+		IncludeOrderParameters: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	order := &res.OrderInfo
+	if status := order.OrderStatus.MajorStatus; status != certcenter.StatusComplete {
+		return nil, fmt.Errorf("store: order %d is not complete (%s)", id, status)
+	}
+	b, err := bundle.FromFulfillment(&order.Fulfillment)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Get(ctx, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if key == nil && existing != nil && existing.Key != "" {
+		if k, err := csr.ParseKey(existing.Key); err == nil && bundle.MatchesKey(b.Leaf, k) {
+			key = k
+		}
+	}
+	entry := &Entry{
+		Metadata: Metadata{
+			CertCenterOrderID: id,
+			CommonName:        order.CommonName,
+			SubjectAltNames:   order.OrderParameters.SubjectAltNames,
+			ProductCode:       order.OrderParameters.ProductCode,
+			PartnerOrderID:    order.OrderParameters.PartnerOrderID,
+			StartDate:         b.Leaf.NotBefore,
+			EndDate:           b.Leaf.NotAfter,
+		},
+	}
+	if existing != nil {
+		entry.Renews = existing.Renews
+	}
+	if entry.CommonName == "" {
+		entry.CommonName = b.Leaf.Subject.CommonName
+	}
+	if len(entry.SubjectAltNames) == 0 {
+		entry.SubjectAltNames = b.Leaf.DNSNames
+	}
+	if key != nil {
+		if err := b.SetKey(key); err != nil {
+			return nil, err
+		}
+		data, err := b.Encode(bundle.KeyPEM, "")
+		if err != nil {
+			return nil, err
+		}
+		entry.Key = string(data)
+	}
+	// encoding certificates as PEM can't fail
+	leaf, _ := b.Encode(bundle.LeafPEM, "")
+	chain, _ := b.Encode(bundle.ChainPEM, "")
+	entry.Certificate, entry.Chain = string(leaf), string(chain)
+
+	if err := s.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// clone returns a deep copy of e
+func (e *Entry) clone() *Entry {
+	c := *e
+	c.SubjectAltNames = append([]string(nil), e.SubjectAltNames...)
+	return &c
+}
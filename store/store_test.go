@@ -0,0 +1,251 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/bundle"
+	"certcenter.com/go/certcentertest"
+	"certcenter.com/go/csr"
+	"certcenter.com/go/store"
+)
+
+// backends returns a fresh instance of each Store implementation
+func backends(t *testing.T) map[string]store.Store {
+	t.Helper()
+	fs, err := store.NewFileStore(filepath.Join(t.TempDir(), "certs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]store.Store{
+		"MemoryStore": new(store.MemoryStore),
+		"FileStore":   fs,
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		if _, err := s.Get(ctx, 1); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: got %v, want ErrNotFound", name, err)
+		}
+		if err := s.Delete(ctx, 1); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: got %v, want ErrNotFound", name, err)
+		}
+
+		for _, id := range []int64{30, 10, 20} {
+			entry := &store.Entry{
+				Metadata:    store.Metadata{CertCenterOrderID: id, CommonName: "www.example.com", SubjectAltNames: []string{"example.com"}},
+				Certificate: "certificate",
+			}
+			if err := s.Put(ctx, entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry.Updated.IsZero() {
+				t.Errorf("%s: Updated not set", name)
+			}
+		}
+		list, err := s.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 || list[0].CertCenterOrderID != 10 || list[1].CertCenterOrderID != 20 || list[2].CertCenterOrderID != 30 {
+			t.Errorf("%s: got %+v, want the entries ordered by CertCenterOrderID", name, list)
+		}
+
+		// entries are copies
+		entry, err := s.Get(ctx, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.SubjectAltNames[0] = "changed.example.com"
+		if entry, _ := s.Get(ctx, 20); entry.SubjectAltNames[0] != "example.com" || entry.Certificate != "certificate" {
+			t.Errorf("%s: got %+v", name, entry)
+		}
+
+		if err := s.Delete(ctx, 20); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Get(ctx, 20); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: got %v after Delete, want ErrNotFound", name, err)
+		}
+		if list, _ := s.List(ctx); len(list) != 2 {
+			t.Errorf("%s: got %d entries, want 2", name, len(list))
+		}
+	}
+}
+
+func TestFileStorePermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs")
+	s, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Put(ctx, &store.Entry{Metadata: store.Metadata{CertCenterOrderID: 1}, Key: "key"}); err != nil {
+		t.Fatal(err)
+	}
+	// files which aren't entries are ignored
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&^0700 != 0 {
+		t.Errorf("directory has mode %v, want at most 0700", perm)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left behind", file.Name())
+		}
+	}
+	info, err = os.Stat(filepath.Join(dir, "1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&^0600 != 0 {
+		t.Errorf("entry has mode %v, want at most 0600", perm)
+	}
+	if list, err := s.List(ctx); err != nil || len(list) != 1 {
+		t.Errorf("got %d entries (%v), want 1", len(list), err)
+	}
+
+	// a broken entry is reported
+	if err := os.WriteFile(filepath.Join(dir, "2.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, 2); err == nil || errors.Is(err, store.ErrNotFound) {
+		t.Errorf("got %v, want the broken entry reported", err)
+	}
+}
+
+func TestFindAndLatest(t *testing.T) {
+	ctx := context.Background()
+	s := new(store.MemoryStore)
+	now := time.Now()
+	for _, m := range []store.Metadata{
+		{CertCenterOrderID: 1, CommonName: "www.example.com", EndDate: now.AddDate(0, 1, 0)},
+		{CertCenterOrderID: 2, CommonName: "WWW.example.com", EndDate: now.AddDate(1, 0, 0)},
+		{CertCenterOrderID: 3, CommonName: "www.example.com", EndDate: now.AddDate(1, 0, 0)},
+		{CertCenterOrderID: 4, CommonName: "www.example.com", Pending: true},
+		{CertCenterOrderID: 5, CommonName: "mail.example.com", EndDate: now.AddDate(2, 0, 0)},
+	} {
+		if err := s.Put(ctx, &store.Entry{Metadata: m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := store.Find(ctx, s, "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, m := range found {
+		ids = append(ids, m.CertCenterOrderID)
+	}
+	if len(ids) != 3 || ids[0] != 3 || ids[1] != 2 || ids[2] != 1 {
+		t.Errorf("got %v, want [3 2 1]", ids)
+	}
+	latest, err := store.Latest(ctx, s, "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.CertCenterOrderID != 3 {
+		t.Errorf("got order %d, want 3", latest.CertCenterOrderID)
+	}
+	if _, err := store.Latest(ctx, s, "shop.example.com"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+
+	pending, err := s.Get(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pending.Bundle(); err == nil {
+		t.Error("expected a pending entry not to be exported")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	s := new(store.MemoryStore)
+
+	pemCSR, key, err := csr.Generate(&csr.Request{CommonName: "www.example.com"}, csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Order(ctx, &certcenter.OrderRequest{
+		OrderParameters: &certcenter.OrderParameters{
+			ProductCode:    "Comodo.PositiveSSL",
+			CSR:            pemCSR,
+			ValidityPeriod: 12,
+			PartnerOrderID: "www-1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := res.CertCenterOrderID
+	if _, err := store.Fetch(ctx, c, s, id, key); err == nil {
+		t.Fatal("expected an incomplete order not to be stored")
+	}
+	if err := srv.CompleteOrder(id); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := csr.GenerateKey(csr.ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Fetch(ctx, c, s, id, other); err == nil {
+		t.Fatal("expected a mismatched key to be refused")
+	}
+
+	entry, err := store.Fetch(ctx, c, s, id, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.CommonName != "www.example.com" || entry.ProductCode != "Comodo.PositiveSSL" || entry.PartnerOrderID != "www-1" ||
+		entry.EndDate.IsZero() || entry.Key == "" || entry.Chain == "" {
+		t.Errorf("got %+v", entry.Metadata)
+	}
+
+	// fetching again without the key keeps it
+	entry, err = store.Fetch(ctx, c, s, id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Key == "" || stored.Key != entry.Key {
+		t.Error("the key has been lost")
+	}
+	b, err := stored.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Key == nil || !bundle.MatchesKey(b.Leaf, key) || len(b.Chain) != 1 {
+		t.Errorf("got leaf %s, %d intermediates, key %v", b.Leaf.Subject, len(b.Chain), b.Key != nil)
+	}
+	if err := b.Verify(srv.CA.Roots()); err != nil {
+		t.Error(err)
+	}
+}
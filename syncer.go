@@ -0,0 +1,377 @@
+package certcenter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChangeKind tells what happened to an order (see OrderChange)
+type ChangeKind int
+
+const (
+	// OrderCreated is emitted when an order is seen for the first time
+	OrderCreated ChangeKind = iota + 1
+	// OrderStatusChanged is emitted when MajorStatus or MinorStatus changed
+	OrderStatusChanged
+	// OrderFulfilled is emitted when an order became COMPLETE
+	OrderFulfilled
+	// OrderRevoked is emitted when an order became REVOKED
+	OrderRevoked
+	// OrderDCVUpdated is emitted when the status of a domain control
+	// validation changed
+	OrderDCVUpdated
+)
+
+var changeKindNames = map[ChangeKind]string{
+	OrderCreated:       "created",
+	OrderStatusChanged: "status changed",
+	OrderFulfilled:     "fulfilled",
+	OrderRevoked:       "revoked",
+	OrderDCVUpdated:    "DCV updated",
+}
+
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// OrderChange is a single change event. A modified order may cause
+// several events, eg. OrderStatusChanged followed by OrderFulfilled.
+type OrderChange struct {
+	Kind  ChangeKind
+	Order OrderInfo
+	// Previous is the last known state of the order (nil for OrderCreated)
+	Previous *OrderState
+}
+
+// ChangeHandler processes change events. Returning an error stops the
+// current Sync; the events of that order are delivered again next time.
+type ChangeHandler func(ctx context.Context, change OrderChange) error
+
+// OrderState is what a Syncer remembers about an order to detect changes
+type OrderState struct {
+	MajorStatus MajorStatus
+	MinorStatus string
+	UpdateDate  time.Time
+	// DCVStatus maps validated domains onto their status
+	DCVStatus map[string]string `json:",omitempty"`
+}
+
+func newOrderState(order *OrderInfo) OrderState {
+	state := OrderState{
+		MajorStatus: order.OrderStatus.MajorStatus,
+		MinorStatus: order.OrderStatus.MinorStatus,
+		UpdateDate:  order.OrderStatus.UpdateDate,
+	}
+	if len(order.DCVStatus) > 0 {
+		state.DCVStatus = make(map[string]string, len(order.DCVStatus))
+		for _, dcv := range order.DCVStatus {
+			state.DCVStatus[dcv.Domain] = dcv.Status
+		}
+	}
+	return state
+}
+
+// Checkpoint is the persistent state of a Syncer
+type Checkpoint struct {
+	// HighWaterMark is the time up to which all changes have been handled
+	HighWaterMark time.Time
+	// Orders holds the last known state of each order
+	Orders map[int64]OrderState
+}
+
+// CheckpointStore persists a Syncer's checkpoint between runs. Load
+// returns a zero Checkpoint if nothing has been saved yet.
+type CheckpointStore interface {
+	Load(ctx context.Context) (Checkpoint, error)
+	Save(ctx context.Context, checkpoint Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint in memory, eg. for tests
+// or for processes which do a full synchronization on each start
+type MemoryCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint Checkpoint
+}
+
+// Load implements CheckpointStore
+func (m *MemoryCheckpointStore) Load(ctx context.Context) (Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpoint.clone(), nil
+}
+
+// Save implements CheckpointStore
+func (m *MemoryCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoint = checkpoint.clone()
+	return nil
+}
+
+// FileCheckpointStore keeps the checkpoint in a JSON file. The file is
+// replaced atomically on each Save.
+type FileCheckpointStore struct {
+	Path string
+}
+
+// Load implements CheckpointStore
+func (f *FileCheckpointStore) Load(ctx context.Context) (Checkpoint, error) {
+	var checkpoint Checkpoint
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+// Save implements CheckpointStore
+func (f *FileCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (c Checkpoint) clone() Checkpoint {
+	orders := make(map[int64]OrderState, len(c.Orders))
+	for id, state := range c.Orders {
+		orders[id] = state
+	}
+	c.Orders = orders
+	return c
+}
+
+// Syncer mirrors order changes by polling GetModifiedOrders. Each Sync
+// continues at the checkpoint's high-water mark, so every change is
+// delivered at least once, even across restarts. Create it with
+// Client.NewSyncer and adjust the exported fields before the first Sync.
+type Syncer struct {
+	client  *Client
+	store   CheckpointStore
+	handler ChangeHandler
+
+	// Since is where the first Sync starts if there is no checkpoint
+	// (default: 90 days ago)
+	Since time.Time
+	// Overlap is subtracted from the high-water mark to catch changes
+	// which became visible late or were stamped by a skewed clock
+	Overlap time.Duration
+	// MaxWindow is the longest timespan requested at once
+	MaxWindow time.Duration
+	// SplitThreshold makes the Syncer halve windows which return at
+	// least that many orders, down to MinWindow (at least one second)
+	SplitThreshold int
+	MinWindow      time.Duration
+	// Retention limits how long the state of an order is kept in the
+	// checkpoint after its last change. Orders changing again after
+	// that are reported as OrderCreated. States of cancelled and
+	// revoked orders are dropped as soon as they can't show up again.
+	Retention time.Duration
+	// Request carries the Include* flags of the queries. IncludeDCVStatus
+	// is always set, FromDate and ToDate are ignored.
+	Request GetModifiedOrdersRequest
+	// Interval is the pause between two passes of Run
+	Interval time.Duration
+}
+
+// NewSyncer returns a Syncer delivering the changes to handler and
+// keeping its checkpoint in store
+func (c *Client) NewSyncer(store CheckpointStore, handler ChangeHandler) *Syncer {
+	return &Syncer{
+		client:         c,
+		store:          store,
+		handler:        handler,
+		Since:          time.Now().AddDate(0, 0, -90),
+		Overlap:        5 * time.Minute,
+		MaxWindow:      24 * time.Hour,
+		SplitThreshold: 500,
+		MinWindow:      time.Minute,
+		Retention:      400 * 24 * time.Hour,
+		Interval:       5 * time.Minute,
+	}
+}
+
+// Run calls Sync every Interval until ctx is done or Sync fails
+func (s *Syncer) Run(ctx context.Context) error {
+	for {
+		if err := s.Sync(ctx); err != nil {
+			return err
+		}
+		if !sleep(ctx, s.Interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// Sync fetches and delivers all changes since the last checkpoint.
+// The checkpoint is saved after each window and when Sync fails.
+// Sync must not be called concurrently for the same store.
+func (s *Syncer) Sync(ctx context.Context) error {
+	checkpoint, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	checkpoint = checkpoint.clone()
+	from := s.Since
+	if !checkpoint.HighWaterMark.IsZero() {
+		from = checkpoint.HighWaterMark.Add(-s.Overlap)
+	}
+	// the API compares timestamps with a resolution of seconds
+	from = from.Truncate(time.Second)
+	now := time.Now().Truncate(time.Second)
+
+	for from.Before(now) {
+		to := now
+		if s.MaxWindow > 0 && to.Sub(from) > s.MaxWindow {
+			to = from.Add(s.MaxWindow)
+		}
+		err := s.window(ctx, &checkpoint, from, to)
+		if err == nil && to.After(checkpoint.HighWaterMark) {
+			checkpoint.HighWaterMark = to
+		}
+		s.forget(&checkpoint)
+		if saveErr := s.store.Save(ctx, checkpoint); err == nil {
+			err = saveErr
+		}
+		if err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
+
+// window fetches the changes of [from, to], splitting it if necessary,
+// and delivers them in the order they happened
+func (s *Syncer) window(ctx context.Context, checkpoint *Checkpoint, from, to time.Time) error {
+	request := s.Request
+	request.FromDate, request.ToDate = from, to
+	request.IncludeDCVStatus = true
+	res, err := s.client.GetModifiedOrders(ctx, &request)
+	if err != nil {
+		return err
+	}
+	minWindow := s.MinWindow
+	if minWindow < time.Second {
+		minWindow = time.Second
+	}
+	middle := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+	if s.SplitThreshold > 0 && len(res.OrderInfos) >= s.SplitThreshold &&
+		to.Sub(from) >= 2*minWindow && middle.After(from) {
+		if err := s.window(ctx, checkpoint, from, middle); err != nil {
+			return err
+		}
+		return s.window(ctx, checkpoint, middle, to)
+	}
+
+	orders := res.OrderInfos
+	sort.SliceStable(orders, func(i, j int) bool {
+		a, b := orders[i].OrderStatus.UpdateDate, orders[j].OrderStatus.UpdateDate
+		if a.Equal(b) {
+			return orders[i].CertCenterOrderID < orders[j].CertCenterOrderID
+		}
+		return a.Before(b)
+	})
+	for i := range orders {
+		if err := s.deliver(ctx, checkpoint, &orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget drops order states which are no longer needed: cancelled and
+// revoked orders older than the next window, and orders which haven't
+// changed for Retention
+func (s *Syncer) forget(checkpoint *Checkpoint) {
+	next := checkpoint.HighWaterMark.Add(-s.Overlap)
+	retained := checkpoint.HighWaterMark.Add(-s.Retention)
+	for id, state := range checkpoint.Orders {
+		final := state.MajorStatus == StatusCancelled || state.MajorStatus == StatusRevoked
+		if final && state.UpdateDate.Before(next) || s.Retention > 0 && state.UpdateDate.Before(retained) {
+			delete(checkpoint.Orders, id)
+		}
+	}
+}
+
+// deliver emits the events of a modified order, unless it has already
+// been handled with the same UpdateDate
+func (s *Syncer) deliver(ctx context.Context, checkpoint *Checkpoint, order *OrderInfo) error {
+	if checkpoint.Orders == nil {
+		checkpoint.Orders = make(map[int64]OrderState)
+	}
+	current := newOrderState(order)
+	var previous *OrderState
+	if state, ok := checkpoint.Orders[order.CertCenterOrderID]; ok {
+		if !current.UpdateDate.After(state.UpdateDate) {
+			return nil
+		}
+		previous = &state
+	}
+	for _, kind := range changes(previous, &current) {
+		if err := s.handler(ctx, OrderChange{Kind: kind, Order: *order, Previous: previous}); err != nil {
+			return err
+		}
+	}
+	checkpoint.Orders[order.CertCenterOrderID] = current
+	return nil
+}
+
+// changes compares two states of an order
+func changes(previous, current *OrderState) []ChangeKind {
+	var kinds []ChangeKind
+	statusChanged := previous == nil || previous.MajorStatus != current.MajorStatus
+	if previous == nil {
+		kinds = append(kinds, OrderCreated)
+	} else if statusChanged || previous.MinorStatus != current.MinorStatus {
+		kinds = append(kinds, OrderStatusChanged)
+	}
+	if statusChanged && current.MajorStatus == StatusComplete {
+		kinds = append(kinds, OrderFulfilled)
+	}
+	if statusChanged && current.MajorStatus == StatusRevoked {
+		kinds = append(kinds, OrderRevoked)
+	}
+	if previous != nil && !sameDCVStatus(previous.DCVStatus, current.DCVStatus) {
+		kinds = append(kinds, OrderDCVUpdated)
+	}
+	return kinds
+}
+
+func sameDCVStatus(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for domain, status := range a {
+		if b[domain] != status {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package certcenter_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+// newSyncServer returns a fake server whose clock lags behind, so
+// changes are older than the end of the windows requested by a Syncer
+func newSyncServer(lag time.Duration) *certcentertest.Server {
+	srv := certcentertest.NewServer()
+	srv.Now = func() time.Time { return time.Now().Add(-lag) }
+	return srv
+}
+
+type changeLog []certcenter.OrderChange
+
+func (l *changeLog) handle(ctx context.Context, change certcenter.OrderChange) error {
+	*l = append(*l, change)
+	return nil
+}
+
+func (l changeLog) kinds(id int64) []certcenter.ChangeKind {
+	var kinds []certcenter.ChangeKind
+	for _, change := range l {
+		if change.Order.CertCenterOrderID == id {
+			kinds = append(kinds, change.Kind)
+		}
+	}
+	return kinds
+}
+
+func equalKinds(a, b []certcenter.ChangeKind) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSyncer(t *testing.T) {
+	srv := newSyncServer(2 * time.Second)
+	defer srv.Close()
+	ctx := context.Background()
+	store := &certcenter.FileCheckpointStore{Path: filepath.Join(t.TempDir(), "checkpoint.json")}
+	var log changeLog
+	syncer := srv.Client().NewSyncer(store, log.handle)
+	syncer.Since = time.Now().Add(-2 * time.Hour)
+
+	first := placeOrder(t, srv, "www.example.com")
+	second := placeOrder(t, srv, "api.example.com")
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 || log[0].Kind != certcenter.OrderCreated || log[0].Order.CertCenterOrderID != first {
+		t.Fatalf("got %v, want two OrderCreated events", log)
+	}
+
+	// nothing changed: the overlap must not deliver events again
+	log = nil
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 0 {
+		t.Fatalf("got %v, want no events", log)
+	}
+
+	srv.CompleteOrder(first)
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []certcenter.ChangeKind{certcenter.OrderStatusChanged, certcenter.OrderFulfilled, certcenter.OrderDCVUpdated}
+	if got := log.kinds(first); !equalKinds(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := log.kinds(second); len(got) != 0 {
+		t.Errorf("got %v for the unchanged order, want none", got)
+	}
+	checkpoint, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Orders[first].MajorStatus != certcenter.StatusComplete || checkpoint.HighWaterMark.IsZero() {
+		t.Errorf("got checkpoint %+v", checkpoint)
+	}
+}
+
+func TestSyncerSplit(t *testing.T) {
+	srv := newSyncServer(0)
+	defer srv.Close()
+	var log changeLog
+	syncer := srv.Client().NewSyncer(&certcenter.MemoryCheckpointStore{}, log.handle)
+	syncer.Since = time.Now().Add(-2 * time.Hour)
+	// all orders share the same second, so windows can't be split far
+	// enough to stay below the threshold
+	syncer.SplitThreshold = 2
+	syncer.MinWindow = 0
+	now := time.Now().Add(-2 * time.Second)
+	srv.Now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		placeOrder(t, srv, "www.example.com")
+	}
+
+	if err := syncer.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 3 {
+		t.Errorf("got %d events, want 3", len(log))
+	}
+}
+
+func TestSyncerForget(t *testing.T) {
+	// changes older than Overlap
+	srv := newSyncServer(time.Hour)
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	store := &certcenter.MemoryCheckpointStore{}
+	var log changeLog
+	syncer := c.NewSyncer(store, log.handle)
+	syncer.Since = time.Now().Add(-2 * time.Hour)
+
+	cancelled := placeOrder(t, srv, "cancelled.example.com")
+	if _, err := c.DeleteOrder(ctx, &certcenter.DeleteOrderRequest{CertCenterOrderID: cancelled}); err != nil {
+		t.Fatal(err)
+	}
+	pending := placeOrder(t, srv, "pending.example.com")
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, _ := store.Load(ctx)
+	if _, ok := checkpoint.Orders[cancelled]; ok {
+		t.Error("the cancelled order is still in the checkpoint")
+	}
+	if _, ok := checkpoint.Orders[pending]; !ok {
+		t.Error("the pending order is missing in the checkpoint")
+	}
+
+	syncer.Retention = 30 * time.Minute
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint, _ := store.Load(ctx); len(checkpoint.Orders) != 0 {
+		t.Errorf("got %d orders after Retention, want 0", len(checkpoint.Orders))
+	}
+	if len(log) != 2 {
+		t.Errorf("got %d events, want 2", len(log))
+	}
+}
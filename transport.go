@@ -0,0 +1,96 @@
+package certcenter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"sync"
+)
+
+var (
+	sharedTransportOnce sync.Once
+	sharedTransport     *http.Transport
+)
+
+// defaultTransport returns the connection pool shared by all clients
+// which neither bring their own transport nor need special TLS settings
+func defaultTransport() *http.Transport {
+	sharedTransportOnce.Do(func() {
+		sharedTransport = newTransport(defaultTLSConfig())
+	})
+	return sharedTransport
+}
+
+// newTransport returns a pooling transport using tlsConfig
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tlsConfig
+	t.MaxIdleConnsPerHost = 16
+	return t
+}
+
+func defaultTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// transport returns the http.RoundTripper for a client without
+// a custom *http.Client
+func (c *Client) transport() http.RoundTripper {
+	if c.roundTripper != nil {
+		return c.roundTripper
+	}
+	if c.tlsConfig != nil {
+		return newTransport(c.tlsConfig)
+	}
+	return defaultTransport()
+}
+
+// ownTLSConfig returns the client's own TLS configuration, creating it if necessary
+func (c *Client) ownTLSConfig() *tls.Config {
+	if c.tlsConfig == nil {
+		c.tlsConfig = defaultTLSConfig()
+	}
+	return c.tlsConfig
+}
+
+// WithRoundTripper lets you provide the http.RoundTripper used to send
+// requests (eg. a proxy-aware transport or a test double). The TLS
+// options have no effect if a RoundTripper is provided.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.roundTripper = rt
+	}
+}
+
+// WithTLSConfig replaces the client's TLS configuration. The client gets
+// its own connection pool instead of the shared one.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = config.Clone()
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version (default: tls.VersionTLS12)
+func WithTLSMinVersion(version uint16) Option {
+	return func(c *Client) {
+		c.ownTLSConfig().MinVersion = version
+	}
+}
+
+// WithRootCAs sets the root certificates used to verify the API's
+// server certificate (default: the system pool)
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Client) {
+		c.ownTLSConfig().RootCAs = pool
+	}
+}
+
+// WithClientCertificates sets certificates presented to the server
+// for TLS client authentication
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return func(c *Client) {
+		c.ownTLSConfig().Certificates = certs
+	}
+}
@@ -0,0 +1,116 @@
+package certcenter_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+// countingTransport counts the requests it passes on
+type countingTransport struct {
+	n int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	rt := new(countingTransport)
+	if _, err := srv.Client(certcenter.WithRoundTripper(rt)).Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rt.n != 1 {
+		t.Errorf("RoundTripper used %d times, want 1", rt.n)
+	}
+
+	// an *http.Client takes precedence
+	ignored, used := new(countingTransport), new(countingTransport)
+	c := srv.Client(certcenter.WithRoundTripper(ignored), certcenter.WithHTTPClient(&http.Client{Transport: used}))
+	if _, err := c.Limit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ignored.n != 0 || used.n != 1 {
+		t.Errorf("RoundTripper used %d times, http.Client %d times; want 0 and 1", ignored.n, used.n)
+	}
+}
+
+func TestSharedConnectionPool(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true}`))
+	}))
+	var conns int32
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	// clients without special settings share their connections
+	for i := 0; i < 3; i++ {
+		c := certcenter.NewClient(certcenter.WithBaseURL(ts.URL), certcenter.WithBearer("token"))
+		if _, err := c.Limit(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("%d connections opened, want 1", n)
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+	ctx := context.Background()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	clientCert := ts.TLS.Certificates[0]
+
+	for _, tc := range []struct {
+		name    string
+		options []certcenter.Option
+		ok      bool
+	}{
+		{"system roots", []certcenter.Option{certcenter.WithClientCertificates(clientCert)}, false},
+		{"no client certificate", []certcenter.Option{certcenter.WithRootCAs(roots)}, false},
+		{"TLS 1.3 required", []certcenter.Option{
+			certcenter.WithRootCAs(roots),
+			certcenter.WithClientCertificates(clientCert),
+			certcenter.WithTLSMinVersion(tls.VersionTLS13),
+		}, false},
+		{"trusted", []certcenter.Option{certcenter.WithRootCAs(roots), certcenter.WithClientCertificates(clientCert)}, true},
+		{"TLS config", []certcenter.Option{certcenter.WithTLSConfig(&tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert},
+		})}, true},
+	} {
+		c := certcenter.NewClient(append([]certcenter.Option{
+			certcenter.WithBaseURL(ts.URL),
+			certcenter.WithBearer("token"),
+		}, tc.options...)...)
+		_, err := c.Limit(ctx)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
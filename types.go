@@ -1,12 +1,12 @@
 package certcenter
 
 import (
-	"fmt"
-	"net/http"
 	"time"
 )
 
 // Bearer represents the authentication token you're going to use
+// with the package-level functions. Use NewClient(WithBearer(..))
+// if you need to talk to several accounts concurrently.
 var Bearer string
 
 // KvStoreAuthorizationKey need to be set if you want to use
@@ -15,6 +15,7 @@ var Bearer string
 // "AlwaysOnSSL KV-Storage Authorization-Key"
 var KvStoreAuthorizationKey string
 
+// Parameter placements, as used in the endpoint table (see endpoints.go)
 const (
 	// CC_PARAM_TYPE_QS is QueryString (eg. ?CertCenterOrderId=123)
 	CC_PARAM_TYPE_QS = 1 << iota
@@ -24,12 +25,6 @@ const (
 	CC_PARAM_TYPE_BODY
 )
 
-func checkErr(err error) {
-	if err != nil {
-		fmt.Println(err)
-	}
-}
-
 // Represents an API request
 type apiRequest struct {
 	method     string
@@ -37,8 +32,9 @@ type apiRequest struct {
 	url        string
 	result     interface{}
 	request    interface{}
-	client     *http.Client
+	client     *Client
 	statusCode int
+	retryAfter time.Duration
 }
 
 // SchemeValidationErrors provides basic fields for scheme validation errors
@@ -94,23 +90,26 @@ type ProductsResult struct {
 // ProductDetailsResult represents a GET /ProductDetails response
 type ProductDetailsResult struct {
 	BasicResultInfo
-	ProductDetails struct {
-		CA                string
-		Currency          string
-		Features          []string
-		Licenses          int
-		MaxValidityPeriod int
-		Price             float64
-		ProductCode       string
-		ProductName       string
-		RefundPeriod      int
-		RenewPeriod       int
-		SANFeatures       []string
-		SANHostPrice      float64
-		SANMaxHosts       int
-		SANPackagePrice   float64
-		SANPackageSize    int
-	}
+	ProductDetails ProductInfo
+}
+
+// ProductInfo holds detailed information on a particular product
+type ProductInfo struct {
+	CA                string
+	Currency          string
+	Features          []string
+	Licenses          int
+	MaxValidityPeriod int
+	Price             float64
+	ProductCode       string
+	ProductName       string
+	RefundPeriod      int
+	RenewPeriod       int
+	SANFeatures       []string
+	SANHostPrice      float64
+	SANMaxHosts       int
+	SANPackagePrice   float64
+	SANPackageSize    int
 }
 
 // ProductDetailsRequest represents a GET /ProductDetails request
@@ -142,21 +141,24 @@ type QuoteRequest struct {
 // ValidateCSRResult represents a POST /ValidateCSR response
 type ValidateCSRResult struct {
 	BasicResultInfo
-	ParsedCSR struct {
-		CommonName             string
-		Organization           string
-		OrganizationUnit       string
-		Email                  string
-		State                  string
-		Locality               string
-		Country                string
-		KeyLength              int
-		SignaturAlgorithm      string
-		KeyEncryptionAlgorithm string
-		HashMD5                string
-		HashSHA256             string
-		UniqueValue            string
-	}
+	ParsedCSR ParsedCSR
+}
+
+// ParsedCSR holds the details of a CSR (see ValidateCSR and ParseCSR)
+type ParsedCSR struct {
+	CommonName             string
+	Organization           string
+	OrganizationUnit       string
+	Email                  string
+	State                  string
+	Locality               string
+	Country                string
+	KeyLength              int
+	SignaturAlgorithm      string
+	KeyEncryptionAlgorithm string
+	HashMD5                string
+	HashSHA256             string
+	UniqueValue            string
 }
 
 // ValidateCSRRequest represents a POST /ValidateCSR request
@@ -221,11 +223,11 @@ type OrderResult struct {
 		PartnerOrderID         string
 		ProductCode            string
 		ServerCount            int
-		SignatureHashAlgorithm string
+		SignatureHashAlgorithm SignatureHashAlgorithm
 		SubjectAltNameCount    int
 		SubjectAltNames        []string
-		ValidityPeriod         int    // 12 or 24 month (days for AlwaysOnSSL, min. 180, max. 365)
-		DVAuthMethod           string // DNS, EMAIL, FILE
+		ValidityPeriod         int          // 12 or 24 month (days for AlwaysOnSSL, min. 180, max. 365)
+		DVAuthMethod           DVAuthMethod // DNS, EMAIL, FILE
 	}
 	// AlwaysOnSSL (Encryption Everywhere) only:
 	Fulfillment struct {
@@ -237,19 +239,19 @@ type OrderResult struct {
 
 // OrderParameters represents generic Order Parameters
 type OrderParameters struct {
-	CSR                    string           `json:",omitempty"` // PEM-encoded PKCS#10
-	IsCompetitiveUpgrade   bool             `json:",omitempty"`
-	IsRenewal              bool             `json:",omitempty"`
-	PartnerOrderID         string           `json:",omitempty"`
-	ProductCode            string           `json:",omitempty"`
-	ServerCount            int              `json:",omitempty"`
-	SignatureHashAlgorithm string           `json:",omitempty"`
-	SubjectAltNameCount    int              `json:",omitempty"`
-	SubjectAltNames        []string         `json:",omitempty"`
-	ValidityPeriod         int              `json:",omitempty"` // 12 or 24 month (days for AlwaysOnSSL, min. 180, max. 365)
-	DVAuthMethod           string           `json:",omitempty"` // DNS, EMAIL, FILE
-	DomainApprovers        *DomainApprovers `json:",omitempty"` // Domain Control Validation
-	ApproverEmail          string           `json:",omitempty"` // deprecated
+	CSR                    string                 `json:",omitempty"` // PEM-encoded PKCS#10
+	IsCompetitiveUpgrade   bool                   `json:",omitempty"`
+	IsRenewal              bool                   `json:",omitempty"`
+	PartnerOrderID         string                 `json:",omitempty"`
+	ProductCode            string                 `json:",omitempty"`
+	ServerCount            int                    `json:",omitempty"`
+	SignatureHashAlgorithm SignatureHashAlgorithm `json:",omitempty"`
+	SubjectAltNameCount    int                    `json:",omitempty"`
+	SubjectAltNames        []string               `json:",omitempty"`
+	ValidityPeriod         int                    `json:",omitempty"` // 12 or 24 month (days for AlwaysOnSSL, min. 180, max. 365)
+	DVAuthMethod           DVAuthMethod           `json:",omitempty"` // DNS, EMAIL, FILE
+	DomainApprovers        *DomainApprovers       `json:",omitempty"` // Domain Control Validation
+	ApproverEmail          string                 `json:",omitempty"` // deprecated
 }
 
 // OrganizationInfo represents organizational information
@@ -296,7 +298,7 @@ type PutApproverEmailResult struct {
 
 // PutApproverEmailRequest represents a PUT /ApproverEmail request
 type PutApproverEmailRequest struct {
-	CertCenterOrderID int64
+	CertCenterOrderID int64 `path:"CertCenterOrderID"`
 	ApproverEmail     string
 }
 
@@ -307,11 +309,11 @@ type ResendApproverEmailResult struct {
 
 // ResendApproverEmailRequest represents a POST /ApproverEmail request
 type ResendApproverEmailRequest struct {
-	CertCenterOrderID int64
+	CertCenterOrderID int64 `path:"CertCenterOrderID"`
 }
 
 type OrderStatus struct {
-	MajorStatus string
+	MajorStatus MajorStatus
 	MinorStatus string
 	OrderDate   time.Time
 	UpdateDate  time.Time
@@ -409,25 +411,39 @@ type GetOrdersResult struct {
 		ItemsAvailable int64
 		ItemsPerPage   int64
 		Page           int64
-		OrderBy        string
-		OrderDir       string
-		Status         []string
-		ProductType    []string
+		OrderBy        OrderBy
+		OrderDir       OrderDir
+		Status         []MajorStatus
+		ProductType    []ProductType
 		CommonName     string
 	} `json:"_meta"`
 }
 
-// GetOrdersRequest represents a GET /Orders request
+// GetOrdersRequest represents a GET /Orders request. All filters are
+// optional; Status and ProductType match any of the given values. The
+// date ranges include their bounds, and a zero bound leaves the range
+// open on that side.
+//
+// Status and ProductType used to be plain strings. Wrap single values
+// in a slice, eg. []MajorStatus{StatusComplete}.
 type GetOrdersRequest struct {
-	Status                   string
-	ProductType              string
-	CommonName               string
-	IncludeFulfillment       bool `url:"includeFulfillment"`
-	IncludeOrderParameters   bool `url:"includeOrderParameters"`
-	IncludeBillingDetails    bool `url:"includeBillingDetails"`
-	IncludeContacts          bool `url:"includeContacts"`
-	IncludeOrganizationInfos bool `url:"includeOrganizationInfos"`
-	IncludeDCVStatus         bool `url:"includeDCVStatus"`
+	Status                   []MajorStatus `url:",comma,omitempty"`
+	ProductType              []ProductType `url:",comma,omitempty"`
+	CommonName               string        `url:",omitempty"`
+	OrderDateFrom            time.Time     `url:",omitempty"`
+	OrderDateTo              time.Time     `url:",omitempty"`
+	ExpirationDateFrom       time.Time     `url:",omitempty"`
+	ExpirationDateTo         time.Time     `url:",omitempty"`
+	Page                     int64         `url:",omitempty"` // starts at 1
+	ItemsPerPage             int64         `url:",omitempty"`
+	OrderBy                  OrderBy       `url:",omitempty"` // defaults to OrderByID
+	OrderDir                 OrderDir      `url:",omitempty"` // defaults to OrderDescending
+	IncludeFulfillment       bool          `url:"includeFulfillment"`
+	IncludeOrderParameters   bool          `url:"includeOrderParameters"`
+	IncludeBillingDetails    bool          `url:"includeBillingDetails"`
+	IncludeContacts          bool          `url:"includeContacts"`
+	IncludeOrganizationInfos bool          `url:"includeOrganizationInfos"`
+	IncludeDCVStatus         bool          `url:"includeDCVStatus"`
 }
 
 // GetModifiedOrdersResult represents a GET /ModifiedOrders response
@@ -456,13 +472,13 @@ type GetOrderResult struct {
 
 // GetOrderRequest represents a GET /Order/:CertCenterOrderID request
 type GetOrderRequest struct {
-	CertCenterOrderID        int64
-	IncludeFulfillment       bool `url:"includeFulfillment"`
-	IncludeOrderParameters   bool `url:"includeOrderParameters"`
-	IncludeBillingDetails    bool `url:"includeBillingDetails"`
-	IncludeContacts          bool `url:"includeContacts"`
-	IncludeOrganizationInfos bool `url:"includeOrganizationInfos"`
-	IncludeDCVStatus         bool `url:"includeDCVStatus"`
+	CertCenterOrderID        int64 `path:"CertCenterOrderID"`
+	IncludeFulfillment       bool  `url:"includeFulfillment"`
+	IncludeOrderParameters   bool  `url:"includeOrderParameters"`
+	IncludeBillingDetails    bool  `url:"includeBillingDetails"`
+	IncludeContacts          bool  `url:"includeContacts"`
+	IncludeOrganizationInfos bool  `url:"includeOrganizationInfos"`
+	IncludeDCVStatus         bool  `url:"includeDCVStatus"`
 }
 
 // DeleteOrderResult represents a DELETE /Order/:CertCenterOrderID response
@@ -472,7 +488,7 @@ type DeleteOrderResult struct {
 
 // DeleteOrderRequest represents a DELETE /Order/:CertCenterOrderID request
 type DeleteOrderRequest struct {
-	CertCenterOrderID int64
+	CertCenterOrderID int64 `path:"CertCenterOrderID"`
 }
 
 // ReissueResult represents a POST /Reissue response
@@ -483,8 +499,8 @@ type ReissueResult struct {
 // ReissueOrderParameters represents the required OrderParameters for POST /Reissue
 type ReissueOrderParameters struct {
 	CSR                    string
-	DVAuthMethod           string
-	SignatureHashAlgorithm string
+	DVAuthMethod           DVAuthMethod
+	SignatureHashAlgorithm SignatureHashAlgorithm
 	DomainApprovers        *DomainApprovers `json:",omitempty"` // Domain Control Validation
 }
 
@@ -504,14 +520,14 @@ type RevokeResult struct {
 
 // RevokeRequest represents a DELETE /Revoke request
 type RevokeRequest struct {
-	CertCenterOrderID int64
+	CertCenterOrderID int64 `path:"CertCenterOrderID"`
 	// optional parameters
-	RevokeReason string `json:",omitempty"`
-	Certificate  string `json:",omitempty"` // PEM encoded X.509 certificate
+	RevokeReason RevokeReason `json:",omitempty"`
+	Certificate  string       `json:",omitempty"` // PEM encoded X.509 certificate
 }
 
 type BaseDomainRequest struct {
-	FQDN string `json:"fqdn"`
+	FQDN string `json:"fqdn" path:"FQDN"`
 }
 
 type BaseDomainResult struct {
@@ -583,7 +599,7 @@ type VulnerabilityAssessmentResult struct {
 // https://developers.certcenter.com/v1/reference#vulnerabilityassessment
 type VulnerabilityAssessmentRequest struct {
 	CertCenterOrderID      int64
-	ServiceStatus          string
+	ServiceStatus          ServiceStatus
 	EmailNotificationLevel string
 }
 
@@ -595,12 +611,12 @@ type VulnerabilityAssessmentRescanResult struct {
 // VulnerabilityAssessmentRescanRequest represents a GET /VulnerabilityAssessmen/:CertCenterOrderID request
 // https://developers.certcenter.com/v1/reference#vulnerabilityassessmentrescan
 type VulnerabilityAssessmentRescanRequest struct {
-	CertCenterOrderID int64
+	CertCenterOrderID int64 `path:"CertCenterOrderID"`
 }
 
 // UserData represents a basic field-set for /User transactions
 type UserData struct {
-	UsernameOrUserId string   `json:",omitempty"`
+	UsernameOrUserId string   `json:",omitempty" path:"UsernameOrUserId"`
 	FullName         string   `json:",omitempty"`
 	Email            string   `json:",omitempty"`
 	Username         string   `json:",omitempty"`
@@ -668,7 +684,7 @@ type DeleteUserResult struct {
 // DeleteUserRequest represents a GET /User/:UsernameOrUserId request
 // https://developers.certcenter.com/v1/reference#deleteuser
 type DeleteUserRequest struct {
-	UsernameOrUserId string
+	UsernameOrUserId string `path:"UsernameOrUserId"`
 }
 
 // KeyValueStoreResult represents a basic kv-storage response
@@ -678,7 +694,7 @@ type KeyValueStoreResult struct {
 
 // KeyValueStoreRequest represents a basic kv-storage request
 type KeyValueStoreRequest struct {
-	Key   string `json:"filename,omitempty"`
+	Key   string `json:"filename,omitempty" path:"Key"`
 	Value string `json:"hash"`
 }
 
@@ -730,7 +746,7 @@ type GetVouchersResult struct {
 // GetVoucherRequest represents a GET /Voucher/:VoucherCode request
 // https://developers.certcenter.com/v1/reference#getvoucher
 type GetVoucherRequest struct {
-	VoucherCode string
+	VoucherCode string `path:"VoucherCode"`
 }
 
 // DeleteVoucherResult represents a DELETE /Voucher/:VoucherCode response
@@ -741,5 +757,5 @@ type DeleteVoucherResult struct {
 // DeleteVoucherRequest represents a DELETE /Voucher/:VoucherCode request
 // https://developers.certcenter.com/v1/reference#deletevoucher
 type DeleteVoucherRequest struct {
-	VoucherCode string
+	VoucherCode string `path:"VoucherCode"`
 }
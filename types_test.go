@@ -0,0 +1,112 @@
+package certcenter_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+func TestGetOrdersFilters(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	pending := placeOrder(t, srv, "pending.example.com")
+	complete := placeOrder(t, srv, "complete.example.com")
+	srv.CompleteOrder(complete)
+	cancelled := placeOrder(t, srv, "cancelled.example.com")
+	if _, err := c.DeleteOrder(ctx, &certcenter.DeleteOrderRequest{CertCenterOrderID: cancelled}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.GetOrders(ctx, &certcenter.GetOrdersRequest{
+		Status:      []certcenter.MajorStatus{certcenter.StatusPending, certcenter.StatusComplete},
+		ProductType: []certcenter.ProductType{certcenter.ProductTypeSSL, certcenter.ProductTypeSMIME},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, order := range res.OrderInfos {
+		ids = append(ids, order.CertCenterOrderID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) != 2 || ids[0] != pending || ids[1] != complete {
+		t.Errorf("got orders %v, want %d and %d", ids, pending, complete)
+	}
+	if len(res.Meta.Status) != 2 || len(res.Meta.ProductType) != 2 {
+		t.Errorf("got Meta %+v, want both filters echoed", res.Meta)
+	}
+
+	res, err = c.GetOrders(ctx, &certcenter.GetOrdersRequest{CommonName: "cancelled.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.OrderInfos) != 1 || res.OrderInfos[0].CertCenterOrderID != cancelled {
+		t.Errorf("got %d orders, want the cancelled one", len(res.OrderInfos))
+	}
+}
+
+func TestGetOrdersDateRanges(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	defer func() { srv.Now = time.Now }()
+
+	// placed and issued 11, 6 and 3 months ago
+	now := time.Now()
+	var ids []int64
+	for i, monthsAgo := range []int{11, 6, 3} {
+		srv.Now = func() time.Time { return now.AddDate(0, -monthsAgo, 0) }
+		id := placeOrder(t, srv, "www.example.com")
+		if i < 2 {
+			srv.CompleteOrder(id)
+		}
+		ids = append(ids, id)
+	}
+	srv.Now = time.Now
+
+	orderIDs := func(req *certcenter.GetOrdersRequest) []int64 {
+		t.Helper()
+		res, err := c.GetOrders(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, order := range res.OrderInfos {
+			got = append(got, order.CertCenterOrderID)
+		}
+		return got
+	}
+
+	// the pending order has no EndDate and never matches an expiration range
+	got := orderIDs(&certcenter.GetOrdersRequest{
+		ExpirationDateFrom: now,
+		OrderBy:            certcenter.OrderByExpirationDate,
+		OrderDir:           certcenter.OrderAscending,
+	})
+	if len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Errorf("got orders %v, want %v soonest expiring first", got, ids[:2])
+	}
+	got = orderIDs(&certcenter.GetOrdersRequest{
+		ExpirationDateFrom: now,
+		ExpirationDateTo:   now.AddDate(0, 2, 0),
+	})
+	if len(got) != 1 || got[0] != ids[0] {
+		t.Errorf("got orders %v, want only %d", got, ids[0])
+	}
+	got = orderIDs(&certcenter.GetOrdersRequest{
+		OrderDateFrom: now.AddDate(0, -7, 0),
+		OrderDateTo:   now,
+		OrderBy:       certcenter.OrderByOrderDate,
+	})
+	if len(got) != 2 || got[0] != ids[2] || got[1] != ids[1] {
+		t.Errorf("got orders %v, want %d and %d, latest first", got, ids[2], ids[1])
+	}
+}
@@ -0,0 +1,379 @@
+package certcenter
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationErrors lists the problems found by the Validate methods,
+// keyed like the API's SchemeValidationErrors (eg. "OrderParameters.CSR")
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "certcenter: invalid request: " + strings.Join(msgs, "; ")
+}
+
+// Is makes ValidationErrors match ErrInvalidRequest
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// validator is implemented by requests which can be checked locally
+type validator interface {
+	Validate() error
+}
+
+// productValidator is implemented by requests which can be checked
+// against the details of the product they name
+type productValidator interface {
+	validator
+	ValidateFor(product *ProductInfo) error
+	productCode() string
+}
+
+// WithValidation makes the client validate requests locally before
+// sending them. Orders, vouchers and redemptions naming a ProductCode
+// are checked against the product's details, which the client keeps in
+// a Catalog of its own. Requests failing validation return
+// ValidationErrors.
+func WithValidation() Option {
+	return func(c *Client) {
+		c.validate = true
+	}
+}
+
+// validateRequest checks request before it is sent (see WithValidation)
+func (c *Client) validateRequest(ctx context.Context, request interface{}) error {
+	if v, ok := request.(productValidator); ok && v.productCode() != "" {
+		c.catalogOnce.Do(func() {
+			c.catalog = c.NewCatalog()
+		})
+		err := c.catalog.validate(ctx, v)
+		var invalid ValidationErrors
+		if err != nil && !errors.As(err, &invalid) {
+			// Without the product's details, the API has to tell
+			return v.Validate()
+		}
+		return err
+	}
+	if v, ok := request.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// validation collects field errors
+type validation struct {
+	errs ValidationErrors
+}
+
+func (v *validation) add(key, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) required(key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "required")
+		return false
+	}
+	return true
+}
+
+func (v *validation) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func hasFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderRules says which order parameters are mandatory
+type orderRules struct {
+	productCode bool // ProductCode and ValidityPeriod
+	csr         bool
+}
+
+func (v *validation) orderParameters(p *OrderParameters, rules orderRules, product *ProductInfo) {
+	if p == nil {
+		v.add("OrderParameters", "required")
+		return
+	}
+	if rules.productCode {
+		v.required("OrderParameters.ProductCode", p.ProductCode)
+	}
+	if product != nil && p.ProductCode != "" && p.ProductCode != product.ProductCode {
+		v.add("OrderParameters.ProductCode", "does not match product %s", product.ProductCode)
+	}
+
+	switch {
+	case p.ValidityPeriod == 0:
+		if rules.productCode {
+			v.add("OrderParameters.ValidityPeriod", "required")
+		}
+	case p.ValidityPeriod < 0:
+		v.add("OrderParameters.ValidityPeriod", "must not be negative")
+	case product != nil:
+		v.validityPeriod(p.ValidityPeriod, product.ProductCode, product)
+	case p.ProductCode != "":
+		v.validityPeriod(p.ValidityPeriod, p.ProductCode, nil)
+	}
+
+	var csr *CSRDetails
+	if p.CSR != "" {
+		csr = v.csr("OrderParameters.CSR", p.CSR)
+	} else if rules.csr {
+		v.add("OrderParameters.CSR", "required")
+	}
+	// CSRs without SANs get the ordered ones, others have to agree
+	if csr != nil && len(csr.DNSNames) > 0 && len(p.SubjectAltNames) > 0 {
+		missing, extra := csr.MismatchedNames(p.SubjectAltNames)
+		if len(missing) > 0 {
+			v.add("OrderParameters.SubjectAltNames", "not requested by the CSR: %s", strings.Join(missing, ", "))
+		}
+		if len(extra) > 0 {
+			v.add("OrderParameters.SubjectAltNames", "requested by the CSR, but not ordered: %s", strings.Join(extra, ", "))
+		}
+	}
+
+	if p.ServerCount < 0 {
+		v.add("OrderParameters.ServerCount", "must not be negative")
+	}
+	if p.SubjectAltNameCount < 0 {
+		v.add("OrderParameters.SubjectAltNameCount", "must not be negative")
+	}
+	if len(p.SubjectAltNames) > 0 && p.SubjectAltNameCount != 0 && p.SubjectAltNameCount != len(p.SubjectAltNames) {
+		v.add("OrderParameters.SubjectAltNameCount", "is %d, but %d SubjectAltNames are given", p.SubjectAltNameCount, len(p.SubjectAltNames))
+	}
+	for i, name := range p.SubjectAltNames {
+		if strings.TrimSpace(name) == "" {
+			v.add(fmt.Sprintf("OrderParameters.SubjectAltNames.%d", i), "must not be empty")
+		}
+	}
+
+	if product == nil {
+		return
+	}
+	sans := p.SubjectAltNameCount
+	if len(p.SubjectAltNames) > sans {
+		sans = len(p.SubjectAltNames)
+	}
+	if sans > product.SANMaxHosts {
+		if product.SANMaxHosts == 0 {
+			v.add("OrderParameters.SubjectAltNames", "%s does not support SubjectAltNames", product.ProductCode)
+		} else {
+			v.add("OrderParameters.SubjectAltNames", "%s supports up to %d SubjectAltNames", product.ProductCode, product.SANMaxHosts)
+		}
+	}
+	for i, name := range p.SubjectAltNames {
+		if strings.HasPrefix(name, "*.") && !hasFeature(product.SANFeatures, "WildcardSAN") {
+			v.add(fmt.Sprintf("OrderParameters.SubjectAltNames.%d", i), "%s does not support wildcard SubjectAltNames", product.ProductCode)
+		}
+	}
+	if csr != nil && strings.HasPrefix(csr.CommonName, "*.") && !hasFeature(product.Features, "WILDCARD") {
+		v.add("OrderParameters.CSR", "%s does not support wildcard common names", product.ProductCode)
+	}
+}
+
+// validityPeriod checks period against the product. AlwaysOnSSL
+// products, recognized by their features or their code, take 180 to 365
+// days, all others 12 or 24 months (see OrderParameters.ValidityPeriod),
+// unless the product details allow for more.
+func (v *validation) validityPeriod(period int, productCode string, product *ProductInfo) {
+	const key = "OrderParameters.ValidityPeriod"
+	limit := 0
+	if product != nil {
+		limit = product.MaxValidityPeriod
+	}
+	switch {
+	case strings.HasPrefix(productCode, "AlwaysOnSSL.") || product != nil && product.Flags().AlwaysOnSSL:
+		if limit <= 0 || limit > 365 {
+			limit = 365
+		}
+		if period < 180 || period > limit {
+			v.add(key, "must be between 180 and %d days for %s", limit, productCode)
+		}
+	case product == nil:
+		if period != 12 && period != 24 {
+			v.add(key, "must be 12 or 24 months for %s", productCode)
+		}
+	case limit > 0 && period > limit:
+		v.add(key, "exceeds the maximum of %d for %s", limit, productCode)
+	}
+}
+
+// csr checks that value is a PEM-encoded PKCS#10 request with a
+// strong enough key and signature
+func (v *validation) csr(key, value string) *CSRDetails {
+	if block, _ := pem.Decode([]byte(value)); block == nil || !strings.HasSuffix(block.Type, "CERTIFICATE REQUEST") {
+		v.add(key, "must be a PEM-encoded PKCS#10 certificate request")
+		return nil
+	}
+	csr, err := ParseCSR(value)
+	if err != nil {
+		v.add(key, "%v", err)
+		return nil
+	}
+	for _, weakness := range csr.Weaknesses() {
+		v.add(key, "%s", weakness)
+	}
+	return csr
+}
+
+// organization checks the data OV and EV products require
+func (v *validation) organization(info *OrganizationInfo, admin, tech *Contact, product *ProductInfo) {
+	if product != nil && (hasFeature(product.Features, "OV") || hasFeature(product.Features, "EV")) {
+		if info == nil {
+			v.add("OrganizationInfo", "required for %s", product.ProductCode)
+		} else {
+			v.required("OrganizationInfo.OrganizationName", info.OrganizationName)
+			if info.OrganizationAddress == nil {
+				v.add("OrganizationInfo.OrganizationAddress", "required for %s", product.ProductCode)
+			}
+		}
+		if admin == nil {
+			v.add("AdminContact", "required for %s", product.ProductCode)
+		}
+	}
+	if info != nil && info.OrganizationAddress != nil {
+		v.address("OrganizationInfo.OrganizationAddress", info.OrganizationAddress)
+	}
+	v.contact("AdminContact", admin)
+	v.contact("TechContact", tech)
+}
+
+func (v *validation) contact(key string, c *Contact) {
+	if c == nil {
+		return
+	}
+	v.required(key+".FirstName", c.FirstName)
+	v.required(key+".LastName", c.LastName)
+	if v.required(key+".Email", c.Email) {
+		v.email(key+".Email", c.Email)
+	}
+	if c.OrganizationAddress != nil {
+		v.address(key+".OrganizationAddress", c.OrganizationAddress)
+	}
+}
+
+func (v *validation) address(key string, a *OrganizationAddress) {
+	if a.Country != "" && len(a.Country) != 2 {
+		v.add(key+".Country", "must be an ISO 3166-1 alpha-2 code")
+	}
+}
+
+func (v *validation) email(key, value string) {
+	at := strings.LastIndex(value, "@")
+	if at < 1 || at == len(value)-1 || strings.ContainsAny(value, " \t\r\n") {
+		v.add(key, "is not a valid email address")
+	}
+}
+
+// Validate checks the request locally, without per-product rules. The
+// ValidityPeriod has to be 12 or 24 months, or 180 to 365 days for
+// AlwaysOnSSL products.
+func (r *OrderRequest) Validate() error {
+	return r.ValidateFor(nil)
+}
+
+// ValidateFor checks the request locally. If product is given (see
+// ProductDetails), its MaxValidityPeriod, SANMaxHosts and Features are
+// enforced as well.
+func (r *OrderRequest) ValidateFor(product *ProductInfo) error {
+	v := new(validation)
+	v.orderParameters(r.OrderParameters, orderRules{productCode: true, csr: true}, product)
+	v.organization(r.OrganizationInfo, r.AdminContact, r.TechContact, product)
+	return v.err()
+}
+
+func (r *OrderRequest) productCode() string {
+	if r.OrderParameters == nil {
+		return ""
+	}
+	return r.OrderParameters.ProductCode
+}
+
+// Validate checks the request locally
+func (r *ReissueRequest) Validate() error {
+	v := new(validation)
+	if r.CertCenterOrderID <= 0 {
+		v.add("CertCenterOrderID", "required")
+	}
+	if v.required("OrderParameters.CSR", r.OrderParameters.CSR) {
+		v.csr("OrderParameters.CSR", r.OrderParameters.CSR)
+	}
+	if r.ReissueEmail != "" {
+		v.email("ReissueEmail", r.ReissueEmail)
+	}
+	return v.err()
+}
+
+// Validate checks the request locally, without per-product rules
+func (r *RedeemVoucherRequest) Validate() error {
+	return r.ValidateFor(nil)
+}
+
+// ValidateFor checks the request locally, enforcing the rules of the
+// voucher's product if given
+func (r *RedeemVoucherRequest) ValidateFor(product *ProductInfo) error {
+	v := new(validation)
+	v.required("VoucherCode", r.VoucherCode)
+	v.orderParameters(r.OrderParameters, orderRules{csr: true}, product)
+	v.organization(r.OrganizationInfo, r.AdminContact, r.TechContact, product)
+	return v.err()
+}
+
+func (r *RedeemVoucherRequest) productCode() string {
+	if r.OrderParameters == nil {
+		return ""
+	}
+	return r.OrderParameters.ProductCode
+}
+
+// Validate checks the request locally, without per-product rules
+func (r *CreateVoucherRequest) Validate() error {
+	return r.ValidateFor(nil)
+}
+
+// ValidateFor checks the request locally, enforcing the rules of
+// product if given
+func (r *CreateVoucherRequest) ValidateFor(product *ProductInfo) error {
+	v := new(validation)
+	v.orderParameters(&r.OrderParameters, orderRules{productCode: true}, product)
+	return v.err()
+}
+
+func (r *CreateVoucherRequest) productCode() string {
+	return r.OrderParameters.ProductCode
+}
+
+// Validate checks the request locally
+func (r *CreateUserRequest) Validate() error {
+	v := new(validation)
+	v.required("Username", r.Username)
+	v.required("FullName", r.FullName)
+	v.required("Password", r.Password)
+	if v.required("Email", r.Email) {
+		v.email("Email", r.Email)
+	}
+	for i, role := range r.Roles {
+		if strings.TrimSpace(role) == "" {
+			v.add(fmt.Sprintf("Roles.%d", i), "must not be empty")
+		}
+	}
+	return v.err()
+}
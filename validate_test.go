@@ -0,0 +1,138 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+// validityErrors returns the messages reported for ValidityPeriod
+func validityErrors(err error) []string {
+	var invalid certcenter.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return nil
+	}
+	var msgs []string
+	for _, fe := range invalid {
+		if fe.Key == "OrderParameters.ValidityPeriod" {
+			msgs = append(msgs, fe.Msg)
+		}
+	}
+	return msgs
+}
+
+func TestValidateWithoutProduct(t *testing.T) {
+	// AlwaysOnSSL products are recognized by their code
+	for _, tc := range []struct {
+		productCode string
+		period      int
+		invalid     bool
+	}{
+		{"Comodo.PositiveSSL", 12, false},
+		{"Comodo.PositiveSSL", 24, false},
+		{"Comodo.PositiveSSL", 36, true},
+		{"Comodo.PositiveSSL", 180, true},
+		{"AlwaysOnSSL.AlwaysOnSSL", 12, true},
+		{"AlwaysOnSSL.AlwaysOnSSL", 180, false},
+		{"AlwaysOnSSL.AlwaysOnSSL", 365, false},
+		{"AlwaysOnSSL.AlwaysOnSSL", 366, true},
+	} {
+		order := newOrder(t, "www.example.com", "")
+		order.OrderParameters.ProductCode = tc.productCode
+		order.OrderParameters.ValidityPeriod = tc.period
+		msgs := validityErrors(order.Validate())
+		if invalid := len(msgs) > 0; invalid != tc.invalid {
+			t.Errorf("%s for %d: got %q, want invalid=%v", tc.productCode, tc.period, msgs, tc.invalid)
+		}
+	}
+
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.ValidityPeriod = -12
+	if msgs := validityErrors(order.Validate()); len(msgs) != 1 {
+		t.Errorf("negative ValidityPeriod: got %q", msgs)
+	}
+}
+
+func TestValidateForProductFeatures(t *testing.T) {
+	// AlwaysOnSSL is recognized by the product's features, too
+	free := &certcenter.ProductInfo{
+		ProductCode:       "Example.FreeDV",
+		Features:          []string{"DV", "AlwaysOnSSL"},
+		MaxValidityPeriod: 365,
+		SANMaxHosts:       1,
+	}
+	paid := &certcenter.ProductInfo{
+		ProductCode:       "Example.PaidDV",
+		Features:          []string{"DV"},
+		MaxValidityPeriod: 36,
+	}
+	for _, tc := range []struct {
+		product *certcenter.ProductInfo
+		period  int
+		invalid bool
+	}{
+		{free, 90, true},
+		{free, 180, false},
+		{free, 365, false},
+		{free, 400, true},
+		{paid, 12, false},
+		{paid, 24, false},
+		{paid, 36, false},
+		{paid, 48, true},
+	} {
+		order := newOrder(t, "www.example.com", "")
+		order.OrderParameters.ProductCode = tc.product.ProductCode
+		order.OrderParameters.ValidityPeriod = tc.period
+		msgs := validityErrors(order.ValidateFor(tc.product))
+		if invalid := len(msgs) > 0; invalid != tc.invalid {
+			t.Errorf("%s for %d: got %q, want invalid=%v", tc.product.ProductCode, tc.period, msgs, tc.invalid)
+		}
+	}
+}
+
+func TestWithValidationUsesProductDetails(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithValidation())
+	ctx := context.Background()
+
+	order := newOrder(t, "www.example.com", "")
+	order.OrderParameters.ValidityPeriod = 36 // PositiveSSL allows up to 24
+	if _, err := c.Order(ctx, order); len(validityErrors(err)) == 0 {
+		t.Fatalf("got %v, want a ValidityPeriod error", err)
+	}
+	order.OrderParameters.ValidityPeriod = 24
+	if _, err := c.Order(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	order.OrderParameters.ProductCode = "Example.Unknown"
+	if _, err := c.Order(ctx, order); !errors.Is(err, certcenter.ErrInvalidRequest) {
+		t.Fatalf("got %v, want ErrInvalidRequest for an unknown product", err)
+	}
+
+	if n := srv.Calls("Order"); n != 1 {
+		t.Errorf("Order reached the server %d times, want 1", n)
+	}
+	if n := srv.Calls("Products"); n != 1 {
+		t.Errorf("products loaded %d times, want 1", n)
+	}
+}
+
+func TestWithValidationWithoutProductDetails(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client(certcenter.WithValidation())
+
+	// If the products can't be loaded, the API gets the final say
+	srv.FailNext("Products", certcentertest.Failure{StatusCode: 503})
+	order := newOrder(t, "www.example.com", "")
+	if _, err := c.Order(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("Order"); n != 1 {
+		t.Errorf("Order reached the server %d times, want 1", n)
+	}
+}
@@ -0,0 +1,286 @@
+package certcenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOrderNotFulfilled is reported by WaitForOrder and Watcher if an
+// order has been cancelled or revoked instead of being completed
+var ErrOrderNotFulfilled = errors.New("certcenter: order ended without being fulfilled")
+
+// WaitOptions configures WaitForOrder and Watcher. The zero value
+// polls every 10 seconds at first, backing off to every 5 minutes.
+type WaitOptions struct {
+	// MinInterval is the first polling interval. It's used again
+	// whenever the order's status changed.
+	MinInterval time.Duration
+	// MaxInterval caps the polling interval while nothing changes
+	MaxInterval time.Duration
+	// OnUpdate is called whenever MajorStatus, MinorStatus or Progress
+	// of an order changed, and once when it's done. Watchers may call
+	// it concurrently for different orders.
+	OnUpdate func(OrderUpdate)
+	// Include carries the Include* flags of the final GetOrder.
+	// IncludeFulfillment is always set.
+	Include GetOrderRequest
+}
+
+func (o *WaitOptions) intervals() (min, max time.Duration) {
+	min, max = 10*time.Second, 5*time.Minute
+	if o.MinInterval > 0 {
+		min = o.MinInterval
+	}
+	if o.MaxInterval > 0 {
+		max = o.MaxInterval
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// OrderUpdate reports a status transition of a watched order
+type OrderUpdate struct {
+	CertCenterOrderID int64
+	Previous          OrderStatus // zero on the first poll
+	Status            OrderStatus
+	// Done is set on the last update of an order. Order holds the final
+	// OrderInfo (including fulfillment), unless Err is set.
+	Done  bool
+	Order *OrderInfo
+	Err   error
+}
+
+// final reports whether an order won't change anymore
+func final(status *OrderStatus) bool {
+	switch status.MajorStatus {
+	case StatusComplete, StatusCancelled, StatusRevoked:
+		return true
+	}
+	return false
+}
+
+// WaitForOrder polls an order until it's COMPLETE and returns it with
+// fulfillment included. If the order gets cancelled or revoked, its
+// OrderInfo is returned along with ErrOrderNotFulfilled. Transient
+// errors (network errors, 429 and 5xx responses) don't end the wait;
+// other errors (eg. ErrNotFound) and the end of ctx do.
+//
+//	res, err := client.Order(ctx, request)
+//	..
+//	order, err := client.WaitForOrder(ctx, res.CertCenterOrderID, nil)
+func (c *Client) WaitForOrder(ctx context.Context, id int64, opts *WaitOptions) (*OrderInfo, error) {
+	if opts == nil {
+		opts = new(WaitOptions)
+	}
+	return c.watch(ctx, id, opts, opts.OnUpdate)
+}
+
+// watch polls order id with adaptive backoff and reports its transitions
+func (c *Client) watch(ctx context.Context, id int64, opts *WaitOptions, emit func(OrderUpdate)) (*OrderInfo, error) {
+	if emit == nil {
+		emit = func(OrderUpdate) {}
+	}
+	done := func(order *OrderInfo, previous OrderStatus, err error) (*OrderInfo, error) {
+		update := OrderUpdate{CertCenterOrderID: id, Previous: previous, Done: true, Order: order, Err: err}
+		if order != nil {
+			update.Status = order.OrderStatus
+		}
+		emit(update)
+		return order, err
+	}
+
+	min, max := opts.intervals()
+	interval := min
+	var previous OrderStatus
+	for {
+		res, err := c.GetOrder(ctx, &GetOrderRequest{CertCenterOrderID: id})
+		if err == nil && final(&res.OrderInfo.OrderStatus) {
+			include := opts.Include
+			include.CertCenterOrderID = id
+			include.IncludeFulfillment = true
+			res, err = c.GetOrder(ctx, &include)
+			if err == nil {
+				if status := res.OrderInfo.OrderStatus.MajorStatus; status != StatusComplete {
+					err = fmt.Errorf("%w (%s)", ErrOrderNotFulfilled, status)
+				}
+				return done(&res.OrderInfo, previous, err)
+			}
+		}
+		switch {
+		case err != nil && !retryable(ctx, err):
+			return done(nil, previous, err)
+		case err != nil:
+			// a transient failure (eg. 502 or a timeout) doesn't end
+			// the wait, it's treated like an unchanged status
+			if interval = interval * 3 / 2; interval > max {
+				interval = max
+			}
+		default:
+			status := res.OrderInfo.OrderStatus
+			changed := status.MajorStatus != previous.MajorStatus ||
+				status.MinorStatus != previous.MinorStatus ||
+				status.Progress != previous.Progress
+			if changed {
+				emit(OrderUpdate{CertCenterOrderID: id, Previous: previous, Status: status})
+				interval = min
+			} else if interval = interval * 3 / 2; interval > max {
+				interval = max
+			}
+			previous = status
+		}
+		if !sleep(ctx, interval) {
+			return done(nil, previous, ctx.Err())
+		}
+	}
+}
+
+// Watcher waits for several orders at once. Updates are passed to
+// WaitOptions.OnUpdate and, if Updates has been called, sent to the
+// channel it returns.
+//
+//	w := client.NewWatcher(nil)
+//	updates := w.Updates()
+//	w.Add(id1)
+//	w.Add(id2)
+//	go func() {
+//		w.Wait()
+//		w.Close()
+//	}()
+//	for update := range updates {
+//		if update.Done {
+//			..
+//		}
+//	}
+type Watcher struct {
+	client *Client
+	opts   WaitOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	orders  map[int64]context.CancelFunc
+	updates chan OrderUpdate
+	closed  bool
+}
+
+// NewWatcher returns a Watcher which polls according to opts
+func (c *Client) NewWatcher(opts *WaitOptions) *Watcher {
+	w := &Watcher{
+		client: c,
+		orders: make(map[int64]context.CancelFunc),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	return w
+}
+
+// Add starts watching an order. Orders which are watched already are
+// ignored.
+func (w *Watcher) Add(id int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.orders[id]; ok || w.closed {
+		return
+	}
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.orders[id] = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.client.watch(ctx, id, &w.opts, w.emit)
+		w.mu.Lock()
+		delete(w.orders, id)
+		w.mu.Unlock()
+		cancel()
+	}()
+}
+
+// Remove stops watching an order. A final update with the context's
+// error is still delivered.
+func (w *Watcher) Remove(id int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cancel, ok := w.orders[id]; ok {
+		cancel()
+	}
+}
+
+// Len returns the number of orders being watched
+func (w *Watcher) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.orders)
+}
+
+// Updates returns a channel receiving all updates from now on, so call
+// it before adding orders. It's closed by Close. Once requested, the
+// channel has to be drained, as the watcher blocks until updates are
+// received.
+func (w *Watcher) Updates() <-chan OrderUpdate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.updates == nil {
+		if w.closed {
+			// Close may be about to close w.updates, so it stays nil
+			return closedUpdates
+		}
+		w.updates = make(chan OrderUpdate, 16)
+	}
+	return w.updates
+}
+
+// closedUpdates is returned by Updates of closed Watchers
+var closedUpdates = func() chan OrderUpdate {
+	c := make(chan OrderUpdate)
+	close(c)
+	return c
+}()
+
+// Wait blocks until all orders are done
+func (w *Watcher) Wait() {
+	w.wg.Wait()
+}
+
+// Close stops watching all orders and closes the Updates channel.
+// Updates which have not been received yet may get lost.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	w.cancel()
+	w.wg.Wait()
+	w.mu.Lock()
+	if w.updates != nil {
+		close(w.updates)
+	}
+	w.mu.Unlock()
+}
+
+func (w *Watcher) emit(update OrderUpdate) {
+	if w.opts.OnUpdate != nil {
+		w.opts.OnUpdate(update)
+	}
+	w.mu.Lock()
+	updates := w.updates
+	w.mu.Unlock()
+	if updates != nil {
+		// updates of a closed watcher may be dropped
+		select {
+		case updates <- update:
+		case <-w.ctx.Done():
+		}
+	}
+}
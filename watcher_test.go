@@ -0,0 +1,161 @@
+package certcenter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	certcenter "certcenter.com/go"
+	"certcenter.com/go/certcentertest"
+)
+
+var fastPolling = certcenter.WaitOptions{MinInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+
+func TestWaitForOrder(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	id := placeOrder(t, srv, "www.example.com")
+
+	var updates []certcenter.OrderUpdate
+	opts := fastPolling
+	opts.OnUpdate = func(u certcenter.OrderUpdate) {
+		updates = append(updates, u)
+		if !u.Done && u.Status.MajorStatus == certcenter.StatusPending {
+			srv.CompleteOrder(id)
+		}
+	}
+	order, err := srv.Client().WaitForOrder(context.Background(), id, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderStatus.MajorStatus != certcenter.StatusComplete || order.Fulfillment.Certificate == "" {
+		t.Errorf("got %+v, want a fulfilled order", order.OrderStatus)
+	}
+	if len(updates) != 2 || !updates[1].Done || updates[1].Previous.MajorStatus != certcenter.StatusPending {
+		t.Errorf("got updates %+v, want pending and done", updates)
+	}
+}
+
+func TestWaitForOrderTransientErrors(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	id := placeOrder(t, srv, "www.example.com")
+
+	// no retry policy: the wait itself has to survive the failures
+	srv.FailNext("GetOrder", certcentertest.Failure{StatusCode: http.StatusBadGateway, Times: 2})
+	srv.FailNext("GetOrder", certcentertest.Failure{StatusCode: http.StatusTooManyRequests})
+	opts := fastPolling
+	opts.OnUpdate = func(u certcenter.OrderUpdate) {
+		if !u.Done {
+			srv.CompleteOrder(id)
+		}
+	}
+	if _, err := srv.Client().WaitForOrder(context.Background(), id, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("GetOrder"); n < 5 {
+		t.Errorf("got %d polls, want at least 5", n)
+	}
+}
+
+func TestWaitForOrderPermanentErrors(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+
+	if _, err := c.WaitForOrder(ctx, 1, &fastPolling); !errors.Is(err, certcenter.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if _, err := srv.Client(certcenter.WithBearer("wrong")).WaitForOrder(ctx, 1, &fastPolling); !errors.Is(err, certcenter.ErrUnauthorized) {
+		t.Errorf("got %v, want ErrUnauthorized", err)
+	}
+
+	id := placeOrder(t, srv, "www.example.com")
+	c.DeleteOrder(ctx, &certcenter.DeleteOrderRequest{CertCenterOrderID: id})
+	order, err := c.WaitForOrder(ctx, id, &fastPolling)
+	if !errors.Is(err, certcenter.ErrOrderNotFulfilled) || order == nil || order.OrderStatus.MajorStatus != certcenter.StatusCancelled {
+		t.Errorf("got %v, want ErrOrderNotFulfilled with the cancelled order", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitForOrder(ctx, placeOrder(t, srv, "www.example.com"), &fastPolling); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	ids := []int64{
+		placeOrder(t, srv, "a.example.com"),
+		placeOrder(t, srv, "b.example.com"),
+		placeOrder(t, srv, "c.example.com"),
+	}
+
+	w := srv.Client().NewWatcher(&fastPolling)
+	updates := w.Updates()
+	for _, id := range ids {
+		w.Add(id)
+	}
+	w.Add(ids[0]) // ignored
+	go func() {
+		w.Wait()
+		w.Close()
+	}()
+	srv.CompleteOrder(ids[0])
+	srv.CompleteOrder(ids[1])
+	w.Remove(ids[2])
+
+	done := make(map[int64]certcenter.OrderUpdate)
+	for u := range updates {
+		if u.Done {
+			done[u.CertCenterOrderID] = u
+		}
+	}
+	for _, id := range ids[:2] {
+		if u := done[id]; u.Err != nil || u.Order == nil || u.Order.Fulfillment.Certificate == "" {
+			t.Errorf("order %d: got %+v, want it fulfilled", id, u)
+		}
+	}
+	if u := done[ids[2]]; !errors.Is(u.Err, context.Canceled) {
+		t.Errorf("removed order: got %v, want context.Canceled", u.Err)
+	}
+	if w.Len() != 0 {
+		t.Errorf("still watching %d orders", w.Len())
+	}
+}
+
+func TestWatcherCloseUpdates(t *testing.T) {
+	srv := certcentertest.NewServer()
+	defer srv.Close()
+	id := placeOrder(t, srv, "www.example.com")
+
+	// hold Close while it waits for the watched order to finish
+	release := make(chan struct{})
+	opts := fastPolling
+	opts.OnUpdate = func(u certcenter.OrderUpdate) {
+		if u.Done {
+			<-release
+		}
+	}
+	w := srv.Client().NewWatcher(&opts)
+	w.Add(id)
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// Updates called while Close is in progress must not hand out a
+	// channel Close is going to close again
+	updates := w.Updates()
+	close(release)
+	<-closed
+	for range updates {
+	}
+}